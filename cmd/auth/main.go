@@ -3,16 +3,19 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 
+	"github.com/Nivl/trakt-netflix/internal/app"
 	"github.com/Nivl/trakt-netflix/internal/trakt"
 	"github.com/Nivl/trakt-netflix/internal/ui"
-	"github.com/sethvargo/go-envconfig"
 )
 
 type appConfig struct {
 	Trakt trakt.ClientConfig `env:",prefix=TRAKT_"`
+	// ProfilesFile is the path of a file listing the profiles to sync.
+	ProfilesFile string `env:"PROFILES_FILE"`
 }
 
 func main() {
@@ -23,17 +26,39 @@ func main() {
 }
 
 func run() (err error) {
+	profile := flag.String("profile", "", "name of the profile to authenticate. Required if there are multiple profiles")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file. Can also be set with CONFIG_FILE")
+	flag.Parse()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	file, err := app.LoadFile(*configPath)
+	if err != nil {
+		return fmt.Errorf("load config file: %w", err)
+	}
+
 	var cfg appConfig
-	if err = envconfig.Process(ctx, &cfg); err != nil {
-		return fmt.Errorf("parse the env: %w", err)
+	if err = file.Process(ctx, &cfg); err != nil {
+		return fmt.Errorf("parse the config: %w", err)
 	}
 
-	traktClient, err := trakt.NewClient(cfg.Trakt)
-	if err != nil {
-		return fmt.Errorf("create trakt client: %w", err)
+	var traktClient *trakt.Client
+	if !file.HasProfiles() && cfg.ProfilesFile == "" {
+		traktClient, err = trakt.NewClient(cfg.Trakt)
+		if err != nil {
+			return fmt.Errorf("create trakt client: %w", err)
+		}
+	} else {
+		p, err := findProfile(ctx, file, cfg.ProfilesFile, *profile)
+		if err != nil {
+			return err
+		}
+		t, err := app.NewTracker(p)
+		if err != nil {
+			return fmt.Errorf("setup profile: %w", err)
+		}
+		traktClient = t.Trakt
 	}
 
 	err = ui.Authenticate(ctx, traktClient)
@@ -42,3 +67,17 @@ func run() (err error) {
 	}
 	return nil
 }
+
+// findProfile returns the profile with the given name. The name can
+// be empty if there's only one profile.
+func findProfile(ctx context.Context, file *app.File, path, name string) (*app.Profile, error) {
+	profiles, err := app.LoadProfiles(ctx, file, path)
+	if err != nil {
+		return nil, fmt.Errorf("load profiles: %w", err)
+	}
+	p, err := app.FindProfile(profiles, name)
+	if err != nil {
+		return nil, fmt.Errorf("-profile: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,46 @@
+// Package main contains the entry point of the binary that prints what
+// would be synced, without syncing anything
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	_ "time/tzdata" // The docker image doesn't ship with a timezone database
+
+	"github.com/Nivl/trakt-netflix/internal/app"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file. Can also be set with CONFIG_FILE")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	profiles, err := app.Load(ctx, *configPath, nil)
+	if err != nil {
+		return err
+	}
+
+	trackers := make([]*app.Tracker, 0, len(profiles))
+	for _, p := range profiles {
+		p.Trakt.DryRun = true
+		t, err := app.NewTracker(p)
+		if err != nil {
+			return fmt.Errorf("setup profile %q: %w", p.Name, err)
+		}
+		trackers = append(trackers, t)
+	}
+
+	return app.DryRun(ctx, os.Stdout, trackers)
+}
@@ -0,0 +1,156 @@
+// Package main contains the entry point of the binary that imports the
+// ViewingActivity.csv file of Netflix's personal information export
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+	_ "time/tzdata" // The docker image doesn't ship with a timezone database
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/app"
+	"github.com/Nivl/trakt-netflix/internal/errutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/slack"
+	"github.com/Nivl/trakt-netflix/internal/ui"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	filePath := flag.String("file", "", "path of the ViewingActivity.csv file (required)")
+	profile := flag.String("profile", "", "name of the Netflix profile to import. Required if the file contains multiple profiles")
+	syncProfile := flag.String("sync-profile", "", "name of the profile of the profiles file to import into. Required if there are multiple profiles")
+	minDuration := flag.Duration("min-duration", 5*time.Minute, "minimum duration of a viewing, shorter viewings are skipped")
+	since := flag.String("since", "", "date (YYYY-MM-DD) of the first day to import. Defaults to the first viewing")
+	until := flag.String("until", "", "date (YYYY-MM-DD) of the last day to import. Defaults to the last viewing")
+	dryRun := flag.Bool("dry-run", false, "print what would be imported without sending anything to Trakt")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file. Can also be set with CONFIG_FILE")
+	flag.Parse()
+
+	if *filePath == "" {
+		return errors.New("-file is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	profiles, err := app.Load(ctx, *configPath, nil)
+	if err != nil {
+		return err
+	}
+	p, err := app.FindProfile(profiles, *syncProfile)
+	if err != nil {
+		return fmt.Errorf("-sync-profile: %w", err)
+	}
+	p.Trakt.DryRun = *dryRun
+
+	t, err := app.NewTracker(p)
+	if err != nil {
+		return fmt.Errorf("setup profile: %w", err)
+	}
+
+	filter := netflix.ViewingActivityCSVFilter{
+		Profile:     *profile,
+		MinDuration: *minDuration,
+		Since:       time.Time{},
+		Until:       time.Time{},
+	}
+	if *since != "" {
+		filter.Since, err = time.ParseInLocation(time.DateOnly, *since, t.Netflix.Location)
+		if err != nil {
+			return fmt.Errorf("parse -since: %w", err)
+		}
+	}
+	if *until != "" {
+		filter.Until, err = time.ParseInLocation(time.DateOnly, *until, t.Netflix.Location)
+		if err != nil {
+			return fmt.Errorf("parse -until: %w", err)
+		}
+		// The last day is included
+		filter.Until = filter.Until.AddDate(0, 0, 1)
+	}
+	items, err := readFile(*filePath, filter, t.Netflix.Location)
+	if err != nil {
+		return err
+	}
+
+	// Searching Trakt doesn't require to be authenticated
+	if !t.Trakt.IsAuthenticated() && !*dryRun {
+		if err = ui.Authenticate(ctx, t.Trakt); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	if *dryRun {
+		return printMatches(ctx, t.Activity, items)
+	}
+
+	slog.InfoContext(ctx, "Trakt info: starting import", "items", len(items))
+	// The service may be syncing the profile at the same time
+	var summary *activitytracker.Summary
+	err = t.Do(ctx, func() (err error) {
+		summary, err = t.Activity.Import(ctx, *profile, items)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	slog.InfoContext(ctx, "Import completed",
+		"added", summary.Count(activitytracker.ItemStatusAdded),
+		"notFound", summary.Count(activitytracker.ItemStatusNotFound),
+		"unmatched", summary.Count(activitytracker.ItemStatusUnmatched),
+		"lowConfidence", summary.Count(activitytracker.ItemStatusLowConfidence),
+		"needsReview", summary.Count(activitytracker.ItemStatusNeedsReview),
+		"failed", summary.Count(activitytracker.ItemStatusFailed),
+	)
+	return nil
+}
+
+func readFile(path string, filter netflix.ViewingActivityCSVFilter, loc *time.Location) (items []netflix.HistoryItem, err error) {
+	f, err := os.Open(path) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer errutil.RunAndSetError(f.Close, &err, "close the file")
+
+	items, err = netflix.ReadViewingActivityCSV(f, filter, loc)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return items, nil
+}
+
+// printMatches prints how each item would be matched on Trakt.
+func printMatches(ctx context.Context, c *activitytracker.Client, items []netflix.HistoryItem) error {
+	// A nil Slack client only logs the messages
+	var reporter *slack.Client
+
+	results := make([]activitytracker.DryRunItem, 0, len(items))
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		activity := netflix.ParseTitle(ctx, item.Title, reporter)
+		activity.RawTitle = item.Title
+		activity.Date = item.Date
+		match, err := c.FindMatch(ctx, activity)
+		results = append(results, activitytracker.DryRunItem{
+			Activity: activity,
+			Match:    match,
+			Err:      err,
+		})
+	}
+	return activitytracker.PrintDryRun(os.Stdout, results)
+}
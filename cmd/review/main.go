@@ -0,0 +1,84 @@
+// Package main contains the entry point of the binary that lets the
+// user pick the match of the activity that couldn't be matched
+// automatically
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	_ "time/tzdata" // The docker image doesn't ship with a timezone database
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/app"
+	"github.com/Nivl/trakt-netflix/internal/ui"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	profile := flag.String("profile", "", "name of the profile to review. Required if there are multiple profiles")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file. Can also be set with CONFIG_FILE")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	profiles, err := app.Load(ctx, *configPath, nil)
+	if err != nil {
+		return err
+	}
+	p, err := app.FindProfile(profiles, *profile)
+	if err != nil {
+		return fmt.Errorf("-profile: %w", err)
+	}
+
+	t, err := app.NewTracker(p)
+	if err != nil {
+		return fmt.Errorf("setup profile: %w", err)
+	}
+
+	entries := t.Activity.Reviews()
+	if len(entries) == 0 {
+		fmt.Println("Nothing to review")
+		return nil
+	}
+
+	decisions, err := ui.Review(ctx, os.Stdin, os.Stdout, entries)
+	if err != nil {
+		return fmt.Errorf("review: %w", err)
+	}
+	if len(decisions) == 0 {
+		return nil
+	}
+
+	if !t.Trakt.IsAuthenticated() {
+		if err = ui.Authenticate(ctx, t.Trakt); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	// The service may be syncing the profile at the same time
+	var summary *activitytracker.Summary
+	err = t.Do(ctx, func() (err error) {
+		summary, err = t.Activity.ApplyReviews(ctx, decisions)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply reviews: %w", err)
+	}
+	slog.InfoContext(ctx, "Review completed",
+		"added", summary.Count(activitytracker.ItemStatusAdded),
+		"notFound", summary.Count(activitytracker.ItemStatusNotFound),
+		"failed", summary.Count(activitytracker.ItemStatusFailed),
+	)
+	return nil
+}
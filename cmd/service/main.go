@@ -4,26 +4,33 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // The docker image doesn't ship with a timezone database
 
 	"github.com/Nivl/trakt-netflix/internal/activitytracker"
-	"github.com/Nivl/trakt-netflix/internal/netflix"
-	"github.com/Nivl/trakt-netflix/internal/slack"
-	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/Nivl/trakt-netflix/internal/app"
 	"github.com/Nivl/trakt-netflix/internal/ui"
+	"github.com/Nivl/trakt-netflix/internal/web"
 	"github.com/robfig/cron"
-	"github.com/sethvargo/go-envconfig"
 )
 
+// syncTimeout is the maximum duration of the sync of a profile.
+const syncTimeout = 5 * time.Minute
+
 type appConfig struct {
-	Trakt     trakt.ClientConfig `env:",prefix=TRAKT_"`
-	Slack     slack.Config       `env:",prefix=SLACK_"`
-	Netflix   netflix.Config     `env:",prefix=NETFLIX_"`
-	CronSpecs string             `env:"CRON_SPECS,default=@hourly"`
+	CronSpecs string `env:"CRON_SPECS,default=@hourly"`
+	// ShutdownTimeout is how long the syncs in progress have to
+	// complete when the service stops, before being interrupted.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT,default=8s"`
+	// Web contains the configuration of the web UI.
+	Web web.Config `env:",prefix=WEB_"`
 }
 
 func main() {
@@ -35,55 +42,202 @@ func main() {
 }
 
 func run(ctx context.Context) (err error) {
-	var cfg appConfig
-	if err = envconfig.Process(ctx, &cfg); err != nil {
-		return fmt.Errorf("parse the env: %w", err)
-	}
+	backfill := flag.Bool("backfill", false, "sync the whole viewing activity, then exit")
+	backfillSince := flag.String("backfill-since", "", "date (YYYY-MM-DD) at which the backfill stops. Defaults to the first activity")
+	dryRun := flag.Bool("dry-run", false, "print what would be synced without syncing anything, then exit")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file. Can also be set with CONFIG_FILE")
+	flag.Parse()
 
-	traktClient, err := trakt.NewClient(cfg.Trakt)
+	var cfg appConfig
+	profiles, err := app.Load(ctx, *configPath, &cfg)
 	if err != nil {
-		return fmt.Errorf("create trakt client: %w", err)
+		return err
 	}
 
-	netflixClient, err := netflix.NewClient(cfg.Netflix)
-	if err != nil {
-		return fmt.Errorf("create netflix client: %w", err)
+	// A profile that can't be set up is skipped, so it doesn't prevent
+	// the other profiles from being synced
+	trackers := make([]*app.Tracker, 0, len(profiles))
+	for _, p := range profiles {
+		p.Trakt.DryRun = *dryRun
+		t, err := app.NewTracker(p)
+		if err != nil {
+			slog.ErrorContext(ctx, "could not setup the profile, skipping it", "profile", p.Name, "error", err.Error())
+			continue
+		}
+		// Searching Trakt doesn't require to be authenticated
+		if !t.Trakt.IsAuthenticated() && !*dryRun {
+			if p.Name != "" {
+				fmt.Printf("Authenticating profile %q\n", p.Name)
+			}
+			if err = ui.Authenticate(ctx, t.Trakt); err != nil {
+				slog.ErrorContext(ctx, "could not authenticate the profile, skipping it", "profile", p.Name, "error", err.Error())
+				continue
+			}
+		}
+		trackers = append(trackers, t)
+	}
+	if len(trackers) == 0 {
+		return errors.New("none of the profiles could be set up")
 	}
 
-	slackClient := slack.NewClient(cfg.Slack)
+	if *dryRun {
+		return app.DryRun(ctx, os.Stdout, trackers)
+	}
 
-	if !traktClient.IsAuthenticated() {
-		if err = ui.Authenticate(ctx, traktClient); err != nil {
-			return fmt.Errorf("authenticate: %w", err)
-		}
+	if *backfill {
+		return runBackfill(ctx, trackers, *backfillSince)
 	}
 
-	c := activitytracker.New(traktClient, netflixClient, slackClient)
 	slog.InfoContext(ctx, "Trakt info: starting")
 
+	// syncCtx is canceled to interrupt the syncs that take too long
+	// to complete when the service stops
+	syncCtx, interruptSyncs := context.WithCancel(ctx)
+	defer interruptSyncs()
+
 	crn := cron.New()
 	err = crn.AddFunc(cfg.CronSpecs, func() {
-		processCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
-		defer cancel()
-		process(processCtx, c)
+		// Profiles are processed one after the other, each with its
+		// own timeout, so a failing profile doesn't block the others.
+		// A profile still being synced since the previous tick is
+		// skipped.
+		for _, t := range trackers {
+			_, _ = syncProfile(syncCtx, t) // The outcome is logged
+		}
 	})
 	if err != nil {
 		return fmt.Errorf("setup cron: %w", err)
 	}
 	crn.Start()
+	defer crn.Stop()
+
+	webCtx, stopWeb := context.WithCancel(syncCtx)
+	defer stopWeb()
+	webErrs := make(chan error, 1)
+	if cfg.Web.Addr != "" {
+		srv, err := web.New(cfg.Web, trackers, syncProfile)
+		if err != nil {
+			return fmt.Errorf("setup web UI: %w", err)
+		}
+		go func() {
+			webErrs <- srv.ListenAndServe(webCtx)
+		}()
+	}
 
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	// A web UI that fails stops the service, but the syncs in progress
+	// still get to complete
+	var webErr error
+	webStopped := false
+	select {
+	case <-quit:
+	case webErr = <-webErrs:
+		webStopped = true
+	}
 	slog.InfoContext(ctx, "Trakt info: stopping")
 
+	// The web UI is stopped last, so it can still report what's
+	// going on while the syncs in progress complete
 	crn.Stop()
+	drain(ctx, trackers, cfg.ShutdownTimeout, interruptSyncs)
+	stopWeb()
+	if cfg.Web.Addr != "" && !webStopped {
+		webErr = <-webErrs
+	}
+	if webErr != nil {
+		return fmt.Errorf("web UI: %w", webErr)
+	}
 	return nil
 }
 
-func process(ctx context.Context, c *activitytracker.Client) {
-	if err := c.Run(ctx); err != nil {
-		slog.InfoContext(ctx, "An error occurred during a run", "error", err)
+// drain waits for the operations in progress to complete, and prevents
+// new ones from starting. interrupt is called if the operations take
+// longer than timeout.
+func drain(ctx context.Context, trackers []*app.Tracker, timeout time.Duration, interrupt func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, t := range trackers {
+			t.Close()
+		}
+	}()
+
+	select {
+	case <-done:
 		return
+	case <-time.After(timeout):
 	}
+	slog.WarnContext(ctx, "Trakt info: interrupting the syncs in progress", "timeout", timeout)
+	interrupt()
+	<-done
+}
+
+// syncProfile syncs a profile, with a timeout so a sync that hangs
+// doesn't prevent the profile from being synced again.
+// The outcome of the sync is logged.
+func syncProfile(ctx context.Context, t *app.Tracker) (*activitytracker.Summary, error) {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	summary, err := t.Sync(ctx)
+	switch {
+	case errors.Is(err, app.ErrSyncInProgress):
+		slog.InfoContext(ctx, "Sync skipped, the profile is already being synced", "profile", t.Profile.Name, "reason", err.Error())
+		return nil, err
+	case errors.Is(err, app.ErrClosed):
+		slog.InfoContext(ctx, "Sync skipped, the service is stopping", "profile", t.Profile.Name)
+		return nil, err
+	}
+	if err != nil {
+		slog.InfoContext(ctx, "An error occurred during a run", "profile", t.Profile.Name, "error", err)
+		return nil, err
+	}
+	logSummary(ctx, t, summary)
+	return summary, nil
+}
+
+// runBackfill syncs the whole viewing activity of every profile, down
+// to the provided date. An interrupted backfill resumes where it
+// stopped.
+func runBackfill(ctx context.Context, trackers []*app.Tracker, since string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var errs []error
+	for _, t := range trackers {
+		var cutoff time.Time
+		if since != "" {
+			var err error
+			cutoff, err = time.ParseInLocation(time.DateOnly, since, t.Netflix.Location)
+			if err != nil {
+				return fmt.Errorf("parse -backfill-since: %w", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "Trakt info: starting backfill", "profile", t.Profile.Name, "since", since)
+		var summary *activitytracker.Summary
+		err := t.Do(ctx, func() (err error) {
+			summary, err = t.Activity.Backfill(ctx, cutoff)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("backfill profile %q: %w", t.Profile.Name, err))
+			continue
+		}
+		logSummary(ctx, t, summary)
+	}
+	return errors.Join(errs...)
+}
+
+func logSummary(ctx context.Context, t *app.Tracker, summary *activitytracker.Summary) {
+	slog.InfoContext(ctx, "Run completed",
+		"profile", t.Profile.Name,
+		"added", summary.Count(activitytracker.ItemStatusAdded),
+		"notFound", summary.Count(activitytracker.ItemStatusNotFound),
+		"unmatched", summary.Count(activitytracker.ItemStatusUnmatched),
+		"lowConfidence", summary.Count(activitytracker.ItemStatusLowConfidence),
+		"needsReview", summary.Count(activitytracker.ItemStatusNeedsReview),
+		"failed", summary.Count(activitytracker.ItemStatusFailed),
+	)
 }
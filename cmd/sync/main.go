@@ -0,0 +1,105 @@
+// Package main contains the entry point of the binary that syncs the
+// watch activity once, then exits. It's meant to be run by an external
+// scheduler (cron, systemd timers, Kubernetes CronJobs, etc.)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" // The docker image doesn't ship with a timezone database
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/app"
+)
+
+// Exit codes of the binary.
+const (
+	// exitSuccess means every activity got synced.
+	exitSuccess = 0
+	// exitFailure means nothing could be synced, either because the
+	// setup failed or because the run failed for every profile. A run
+	// that added nothing because Trakt failed to add the activities is
+	// a failed run.
+	exitFailure = 1
+	// exitPartialFailure means the run completed, but some activities
+	// couldn't be synced (unmatched, not found by Trakt, etc.), or the
+	// run failed for some of the profiles.
+	exitPartialFailure = 2
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML config file. Can also be set with CONFIG_FILE")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	profiles, err := app.Load(ctx, *configPath, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not load the configuration", "error", err.Error())
+		return exitFailure
+	}
+
+	failed := 0
+	complete := true
+	for _, p := range profiles {
+		summary, err := syncProfile(ctx, p)
+		if err != nil {
+			slog.ErrorContext(ctx, "An error occurred during a run", "profile", p.Name, "error", err.Error())
+			failed++
+			continue
+		}
+		slog.InfoContext(ctx, "Run completed",
+			"profile", p.Name,
+			"added", summary.Count(activitytracker.ItemStatusAdded),
+			"notFound", summary.Count(activitytracker.ItemStatusNotFound),
+			"unmatched", summary.Count(activitytracker.ItemStatusUnmatched),
+			"lowConfidence", summary.Count(activitytracker.ItemStatusLowConfidence),
+			"needsReview", summary.Count(activitytracker.ItemStatusNeedsReview),
+			"failed", summary.Count(activitytracker.ItemStatusFailed),
+		)
+		if summary.Failed() {
+			slog.ErrorContext(ctx, "Trakt failed to add the activities", "profile", p.Name)
+			failed++
+			continue
+		}
+		complete = complete && summary.Complete()
+	}
+	return exitCode(len(profiles), failed, complete)
+}
+
+// exitCode returns the exit code of a run that failed for failed
+// profiles out of total.
+func exitCode(total, failed int, complete bool) int {
+	switch {
+	case failed == total:
+		return exitFailure
+	case failed > 0 || !complete:
+		return exitPartialFailure
+	default:
+		return exitSuccess
+	}
+}
+
+// syncProfile runs the sync of the given profile once.
+func syncProfile(ctx context.Context, p *app.Profile) (*activitytracker.Summary, error) {
+	t, err := app.NewTracker(p)
+	if err != nil {
+		return nil, fmt.Errorf("setup profile: %w", err)
+	}
+	// There's no one to enter the code when running from a scheduler
+	if !t.Trakt.IsAuthenticated() {
+		return nil, errors.New("not authenticated with Trakt, run /auth first")
+	}
+	return t.Sync(ctx)
+}
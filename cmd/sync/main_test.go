@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc         string
+		total        int
+		failed       int
+		complete     bool
+		expectedCode int
+	}{
+		{
+			desc:         "everything synced",
+			total:        2,
+			failed:       0,
+			complete:     true,
+			expectedCode: exitSuccess,
+		},
+		{
+			desc:         "some activities not synced",
+			total:        2,
+			failed:       0,
+			complete:     false,
+			expectedCode: exitPartialFailure,
+		},
+		{
+			desc:         "one profile failed",
+			total:        2,
+			failed:       1,
+			complete:     true,
+			expectedCode: exitPartialFailure,
+		},
+		{
+			desc:         "every profile failed",
+			total:        2,
+			failed:       2,
+			complete:     true,
+			expectedCode: exitFailure,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expectedCode, exitCode(tc.total, tc.failed, tc.complete))
+		})
+	}
+}
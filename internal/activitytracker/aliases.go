@@ -0,0 +1,143 @@
+package activitytracker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// maxAliasLookups is the maximum number of medias whose aliases are
+// fetched when looking for an activity, since each one needs its own
+// request.
+const maxAliasLookups = 5
+
+// aliasSearchFields are the fields searched by Trakt when looking for
+// an alias.
+var aliasSearchFields = []string{"aliases", "translations"}
+
+// findByAlias looks for the medias having an alias or a translation
+// matching the titles of the activity. It's used when the title
+// displayed by Netflix is a localized or a marketing title, that
+// doesn't match the original title used by Trakt.
+//
+// The medias returned by an alias-aware search are considered first,
+// followed by the results of the regular search.
+// The medias with the closest alias are returned, along with their
+// score.
+func (c *Client) findByAlias(ctx context.Context, h *netflix.WatchActivity, searchType trakt.SearchTypes, query string, results []*trakt.Media) ([]*trakt.Media, float64, error) {
+	response, err := c.traktClient.Search(ctx, trakt.SearchRequest{
+		Type:   searchType,
+		Query:  query,
+		Show:   "",
+		Fields: aliasSearchFields,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("searching Trakt aliases (query=%q, activity=%s): %w", query, h.String(), err)
+	}
+	candidates := mergeCandidates(maxAliasLookups, searchResults(response, searchType), results)
+
+	titles := make(map[*trakt.Media][]string, len(candidates))
+	for _, m := range candidates {
+		titles[m], err = c.alternativeTitles(ctx, m, searchType)
+		if err != nil {
+			return nil, 0, fmt.Errorf("getting Trakt aliases (%s=%q, activity=%s): %w", searchType, m.Title, h.String(), err)
+		}
+	}
+	best, score := bestScored(candidates, func(m *trakt.Media) float64 {
+		return aliasSimilarity(h, titles[m])
+	})
+	return best, score, nil
+}
+
+// alternativeTitles returns the other titles of a media. The aliases
+// are used for the movies, and the translations for the shows.
+func (c *Client) alternativeTitles(ctx context.Context, m *trakt.Media, searchType trakt.SearchTypes) ([]string, error) {
+	id := showLookupID(*m)
+	titles := []string{}
+	if searchType == trakt.SearchTypeShow {
+		translations, err := c.traktClient.GetShowTranslations(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range translations {
+			titles = append(titles, t.Title)
+		}
+		return titles, nil
+	}
+
+	aliases, err := c.traktClient.GetMovieAliases(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range aliases {
+		titles = append(titles, a.Title)
+	}
+	return titles, nil
+}
+
+// aliasSimilarity returns the best similarity between the titles of
+// the activity and the provided aliases.
+func aliasSimilarity(h *netflix.WatchActivity, aliases []string) float64 {
+	score := 0.0
+	for _, alias := range aliases {
+		// Translations may be missing a title
+		if alias == "" {
+			continue
+		}
+		score = max(score, activitySimilarity(h, alias))
+	}
+	return score
+}
+
+// activitySimilarity returns the similarity between the title of the
+// activity, or the one given by its metadata, and a Trakt title.
+func activitySimilarity(h *netflix.WatchActivity, traktTitle string) float64 {
+	score := titleSimilarity(h.Title, traktTitle)
+	if h.Metadata != nil {
+		score = max(score, titleSimilarity(h.Metadata.Title, traktTitle))
+	}
+	return score
+}
+
+// mergeCandidates returns up to limit medias from the provided lists,
+// in order, without duplicates.
+func mergeCandidates(limit int, lists ...[]*trakt.Media) []*trakt.Media {
+	seen := map[int]struct{}{}
+	candidates := []*trakt.Media{}
+	for _, list := range lists {
+		for _, m := range list {
+			if len(candidates) == limit {
+				return candidates
+			}
+			if _, ok := seen[m.IDs.Trakt]; ok {
+				continue
+			}
+			seen[m.IDs.Trakt] = struct{}{}
+			candidates = append(candidates, m)
+		}
+	}
+	return candidates
+}
+
+// searchResults returns the medias of the given type contained in a
+// search response.
+func searchResults(response *trakt.SearchResponse, searchType trakt.SearchTypes) []*trakt.Media {
+	medias := []*trakt.Media{}
+	for i := range response.Results {
+		r := &response.Results[i]
+		if r.Type != searchType {
+			continue
+		}
+		switch searchType {
+		case trakt.SearchTypeMovie:
+			medias = append(medias, &r.Movie)
+		case trakt.SearchTypeShow:
+			medias = append(medias, &r.Show)
+		case trakt.SearchTypeEpisode:
+			// Episodes are not medias on their own
+		}
+	}
+	return medias
+}
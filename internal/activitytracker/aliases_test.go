@@ -0,0 +1,80 @@
+package activitytracker
+
+import (
+	"testing"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliasSimilarity(t *testing.T) {
+	t.Parallel()
+
+	translations := []string{"Extraordinary Attorney Woo", "", "이상한 변호사 우영우", "Woo, una abogada extraordinaria"}
+
+	t.Run("matches a translation", func(t *testing.T) {
+		t.Parallel()
+		assert.InDelta(t, 1, aliasSimilarity(newEpisodeActivity("이상한 변호사 우영우", 1, ""), translations), 0.001)
+	})
+
+	t.Run("matches the title of the metadata", func(t *testing.T) {
+		t.Parallel()
+		metadata := &netflix.Metadata{
+			IsShow:       true,
+			Title:        "Extraordinary Attorney Woo",
+			EpisodeTitle: "",
+			Season:       1,
+			Episode:      0,
+			Runtime:      0,
+			Year:         0,
+		}
+		activity := newEpisodeActivity("Woo Young-woo", 1, "")
+		activity.Metadata = metadata
+		assert.InDelta(t, 1, aliasSimilarity(activity, translations), 0.001)
+	})
+
+	t.Run("unrelated titles", func(t *testing.T) {
+		t.Parallel()
+		assert.Less(t, aliasSimilarity(newEpisodeActivity("Squid Game", 1, ""), translations), testThresholds.lowConfidence)
+	})
+
+	t.Run("no aliases", func(t *testing.T) {
+		t.Parallel()
+		assert.Zero(t, aliasSimilarity(newEpisodeActivity("Squid Game", 1, ""), nil))
+	})
+}
+
+func TestMergeCandidates(t *testing.T) {
+	t.Parallel()
+
+	media := func(id int) *trakt.Media {
+		return &trakt.Media{
+			Title:    "",
+			Year:     0,
+			IDs:      trakt.IDs{Trakt: id, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil},
+			Votes:    0,
+			Homepage: "",
+			Network:  "",
+		}
+	}
+	ids := func(medias []*trakt.Media) []int {
+		res := []int{}
+		for _, m := range medias {
+			res = append(res, m.IDs.Trakt)
+		}
+		return res
+	}
+
+	t.Run("removes duplicates", func(t *testing.T) {
+		t.Parallel()
+		got := mergeCandidates(5, []*trakt.Media{media(1), media(2)}, []*trakt.Media{media(2), media(3)})
+		assert.Equal(t, []int{1, 2, 3}, ids(got))
+	})
+
+	t.Run("stops at the limit", func(t *testing.T) {
+		t.Parallel()
+		got := mergeCandidates(2, []*trakt.Media{media(1)}, []*trakt.Media{media(2), media(3)})
+		assert.Equal(t, []int{1, 2}, ids(got))
+	})
+}
@@ -0,0 +1,120 @@
+package activitytracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/slack"
+)
+
+// backfillCheckpoint keeps track of the progress of a backfill, so
+// it can be resumed if interrupted.
+type backfillCheckpoint struct {
+	// Cutoff is the date the backfill stops at. A checkpoint created
+	// for a different cutoff is discarded.
+	Cutoff time.Time `json:"cutoff"`
+	// NextPage is the next page of the viewing activity to fetch.
+	NextPage int `json:"nextPage"`
+	// Done is true once all the pages have been fetched.
+	Done bool `json:"done"`
+}
+
+// Backfill fetches the whole viewing activity from Netflix, down to
+// cutoff, and marks it as watched on Trakt.
+// A zero cutoff means the whole viewing activity is fetched.
+//
+// The progress is saved after each page, so an interrupted backfill
+// resumes where it stopped when called again with the same cutoff.
+// Pages are added to the retry queue before being synced, so nothing
+// fetched is lost if the sync is interrupted.
+func (c *Client) Backfill(ctx context.Context, cutoff time.Time) (*Summary, error) {
+	checkpoint := &backfillCheckpoint{}
+	if err := c.readCheckpoint(ctx, c.backfillPath, checkpoint); err != nil {
+		return nil, fmt.Errorf("load backfill checkpoint: %w", err)
+	}
+	if !checkpoint.Cutoff.Equal(cutoff) {
+		checkpoint = &backfillCheckpoint{
+			Cutoff:   cutoff,
+			NextPage: 0,
+			Done:     false,
+		}
+	}
+
+	for !checkpoint.Done {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("backfill page %d: %w", checkpoint.NextPage, err)
+		}
+
+		items, err := c.netflixClient.FetchViewingActivity(ctx, checkpoint.NextPage)
+		if err != nil {
+			return nil, fmt.Errorf("fetch viewing activity page %d: %w", checkpoint.NextPage, err)
+		}
+		if len(items) == 0 {
+			checkpoint.Done = true
+		}
+
+		// Pages are sorted from newest to oldest, but we want to
+		// add the oldest activity first
+		slices.Reverse(items)
+		items = slices.DeleteFunc(items, func(item netflix.HistoryItem) bool {
+			if !cutoff.IsZero() && item.Date.Before(cutoff) {
+				checkpoint.Done = true
+				return true
+			}
+			return false
+		})
+
+		// The queue needs to be saved before the checkpoint, otherwise
+		// we could skip a page
+		if err = c.enqueue(ctx, items); err != nil {
+			return nil, err
+		}
+		checkpoint.NextPage++
+		if err = fileutil.WriteJSON(c.backfillPath, checkpoint); err != nil {
+			return nil, fmt.Errorf("write backfill checkpoint: %w", err)
+		}
+	}
+
+	summary, err := c.MarkAsWatched(ctx)
+	if err != nil {
+		return summary, err
+	}
+	if err = c.save(); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// enqueue adds the provided items to the history, and saves their
+// activity in the queue. Items must be sorted from oldest to newest.
+func (c *Client) enqueue(ctx context.Context, items []netflix.HistoryItem) error {
+	// The titles that can't be parsed are only logged, since the
+	// whole viewing activity would flood Slack. A nil Slack client
+	// only logs the messages.
+	var reporter *slack.Client
+	for _, item := range items {
+		c.netflixClient.History.Push(ctx, item, reporter)
+	}
+	c.enqueueNewActivity(time.Now())
+	if err := c.queue.Write(); err != nil {
+		return fmt.Errorf("write retry queue: %w", err)
+	}
+	return nil
+}
+
+// readCheckpoint reads the checkpoint stored at path into v.
+// A checkpoint recovered from its backup is reported, since the
+// progress it lost will be made again.
+func (c *Client) readCheckpoint(ctx context.Context, path string, v any) error {
+	err := fileutil.ReadJSON(path, v)
+	if errors.Is(err, fileutil.ErrRecovered) {
+		c.slackClient.SendMessage(ctx, "The progress got restored from its backup, the last items may be processed again: "+err.Error())
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package activitytracker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillResumes(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2024, time.September, 1, 12, 0, 0, 0, time.UTC)
+	pages := map[int]string{
+		0: fmt.Sprintf(`{"viewedItems":[{"movieID":2,"title":"Movie 2","date":%d},{"movieID":1,"title":"Movie 1","date":%d}]}`, date.AddDate(0, 0, 2).UnixMilli(), date.AddDate(0, 0, 1).UnixMilli()),
+		1: fmt.Sprintf(`{"viewedItems":[{"movieID":0,"title":"Movie 0","date":%d}]}`, date.UnixMilli()),
+		2: `{"viewedItems":[]}`,
+	}
+
+	var mu sync.Mutex
+	requested := []int{}
+	failPage := 2
+	// The metadata of the activity are not available
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /viewingactivity", func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("pg"))
+		assert.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requested = append(requested, page)
+		if page == failPage {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(pages[page]))
+	})
+	netflixAPI := httptest.NewServer(mux)
+	t.Cleanup(netflixAPI.Close)
+
+	// Nothing can be found on Trakt, so the activity stays in the queue
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	c.netflixClient.HTTP = netflixAPI.Client()
+	c.netflixClient.ShaktiURL = netflixAPI.URL
+
+	_, err := c.Backfill(t.Context(), time.Time{})
+	require.Error(t, err, "the backfill should stop on the page that failed")
+	assert.Equal(t, []int{0, 1, 2}, requested)
+	require.Len(t, c.queue.Entries, 3, "the pages fetched should have been queued")
+	// The pages go from the newest to the oldest activity, but each
+	// page is queued from its oldest activity
+	for i, title := range []string{"Movie 1", "Movie 2", "Movie 0"} {
+		assert.Equal(t, title, c.queue.Entries[i].Activity.Title)
+	}
+
+	// The backfill resumes on the page that failed
+	mu.Lock()
+	requested = []int{}
+	failPage = -1
+	mu.Unlock()
+	summary, err := c.Backfill(t.Context(), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, requested)
+	assert.Len(t, summary.Items, 3, "the queued activity should have been synced")
+
+	// A completed backfill doesn't fetch anything
+	requested = []int{}
+	_, err = c.Backfill(t.Context(), time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, requested)
+
+	// A different cutoff starts a new backfill
+	_, err = c.Backfill(t.Context(), date)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, requested)
+}
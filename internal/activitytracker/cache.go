@@ -0,0 +1,234 @@
+package activitytracker
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// CachedShow represents a show that got resolved on Trakt, along with
+// the episodes we fetched.
+type CachedShow struct {
+	Show trakt.Media `json:"show"`
+	// Seasons contains the seasons we fetched, with their episodes.
+	Seasons []trakt.Season `json:"seasons"`
+	// Complete is true when Seasons contains all the seasons of the
+	// show.
+	Complete bool `json:"complete,omitempty"`
+	// Score is the similarity between the Netflix and Trakt titles.
+	Score     float64   `json:"score"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CachedMovie represents a movie that got resolved on Trakt.
+type CachedMovie struct {
+	Movie trakt.Media `json:"movie"`
+	// Score is the similarity between the Netflix and Trakt titles.
+	Score     float64   `json:"score"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CachedMiss represents an activity that couldn't be matched.
+type CachedMiss struct {
+	Error     string    `json:"error"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// MatchCache contains the Netflix titles that have already been
+// resolved on Trakt, so they can be matched again without searching
+// Trakt. It also contains the titles that couldn't be matched, so we
+// don't search for them on every run.
+type MatchCache struct {
+	// Shows is keyed by the parsed title of the show.
+	Shows map[string]*CachedShow `json:"shows"`
+	// Movies is keyed by the Netflix ID of the movie, or by its
+	// title if the ID is unknown.
+	Movies map[string]*CachedMovie `json:"movies"`
+	// Misses is keyed by the parsed title of the activity.
+	Misses map[string]*CachedMiss `json:"misses"`
+
+	path    string
+	ttl     time.Duration
+	missTTL time.Duration
+}
+
+// NewMatchCache creates a new MatchCache, and loads the initial data
+// stored on disk at the given path.
+// Matches expire after ttl, and misses after missTTL. A TTL of 0
+// disables the caching.
+func NewMatchCache(path string, ttl, missTTL time.Duration) (*MatchCache, error) {
+	c := &MatchCache{
+		Shows:   map[string]*CachedShow{},
+		Movies:  map[string]*CachedMovie{},
+		Misses:  map[string]*CachedMiss{},
+		path:    path,
+		ttl:     ttl,
+		missTTL: missTTL,
+	}
+	// The recovery of a file is reported when the files get reloaded
+	// before being used
+	if err := c.load(); err != nil && !errors.Is(err, fileutil.ErrRecovered) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load replaces the content of the cache by the one stored on disk.
+func (c *MatchCache) load() error {
+	c.Shows = map[string]*CachedShow{}
+	c.Movies = map[string]*CachedMovie{}
+	c.Misses = map[string]*CachedMiss{}
+	if err := fileutil.ReadJSON(c.path, c); err != nil {
+		return fmt.Errorf("load match cache: %w", err)
+	}
+	return nil
+}
+
+// Show returns the cached show with the given title.
+func (c *MatchCache) Show(title string, now time.Time) (*CachedShow, bool) {
+	s, ok := c.Shows[cacheKey(title)]
+	if !ok || !now.Before(s.ExpiresAt) {
+		return nil, false
+	}
+	return s, true
+}
+
+// PutShow caches the provided seasons of a show. The seasons are
+// merged with the ones already cached for the same show.
+// score is the similarity between title and the title of the show.
+// complete should be true if seasons contains all the seasons of the
+// show.
+func (c *MatchCache) PutShow(title string, show trakt.Media, score float64, seasons []trakt.Season, complete bool, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	key := cacheKey(title)
+	s, ok := c.Show(title, now)
+	if !ok || s.Show.IDs.Trakt != show.IDs.Trakt {
+		s = &CachedShow{
+			Show:      show,
+			Seasons:   []trakt.Season{},
+			Complete:  false,
+			Score:     score,
+			ExpiresAt: now.Add(c.ttl),
+		}
+		c.Shows[key] = s
+	}
+	if complete {
+		s.Seasons = seasons
+		s.Complete = true
+		return
+	}
+	for _, season := range seasons {
+		i := slices.IndexFunc(s.Seasons, func(cached trakt.Season) bool {
+			return cached.Number == season.Number
+		})
+		if i >= 0 {
+			s.Seasons[i] = season
+			continue
+		}
+		s.Seasons = append(s.Seasons, season)
+	}
+}
+
+// Season returns the cached season with the given number.
+func (s *CachedShow) Season(number int) (*trakt.Season, bool) {
+	for i := range s.Seasons {
+		if s.Seasons[i].Number == number {
+			return &s.Seasons[i], true
+		}
+	}
+	return nil, false
+}
+
+// Movie returns the cached movie of the given activity.
+func (c *MatchCache) Movie(h *netflix.WatchActivity, now time.Time) (*CachedMovie, bool) {
+	m, ok := c.Movies[movieCacheKey(h)]
+	if !ok || !now.Before(m.ExpiresAt) {
+		return nil, false
+	}
+	return m, true
+}
+
+// PutMovie caches the movie of the given activity.
+// score is the similarity between the titles of the activity and of
+// the movie.
+func (c *MatchCache) PutMovie(h *netflix.WatchActivity, movie trakt.Media, score float64, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.Movies[movieCacheKey(h)] = &CachedMovie{
+		Movie:     movie,
+		Score:     score,
+		ExpiresAt: now.Add(c.ttl),
+	}
+}
+
+// Miss returns the error of the last attempt at matching the given
+// activity, if it failed recently.
+func (c *MatchCache) Miss(h *netflix.WatchActivity, now time.Time) (string, bool) {
+	m, ok := c.Misses[missCacheKey(h)]
+	if !ok || !now.Before(m.ExpiresAt) {
+		return "", false
+	}
+	return m.Error, true
+}
+
+// PutMiss records that the given activity couldn't be matched.
+func (c *MatchCache) PutMiss(h *netflix.WatchActivity, err error, now time.Time) {
+	if c.missTTL <= 0 {
+		return
+	}
+	c.Misses[missCacheKey(h)] = &CachedMiss{
+		Error:     err.Error(),
+		ExpiresAt: now.Add(c.missTTL),
+	}
+}
+
+// Invalidate removes the cached match of the given activity, so it
+// gets searched again.
+func (c *MatchCache) Invalidate(h *netflix.WatchActivity) {
+	if h.IsShow {
+		delete(c.Shows, cacheKey(h.Title))
+		return
+	}
+	delete(c.Movies, movieCacheKey(h))
+}
+
+// Write removes the expired entries, and saves the cache to disk.
+func (c *MatchCache) Write(now time.Time) error {
+	maps.DeleteFunc(c.Shows, func(_ string, s *CachedShow) bool { return !now.Before(s.ExpiresAt) })
+	maps.DeleteFunc(c.Movies, func(_ string, m *CachedMovie) bool { return !now.Before(m.ExpiresAt) })
+	maps.DeleteFunc(c.Misses, func(_ string, m *CachedMiss) bool { return !now.Before(m.ExpiresAt) })
+	return fileutil.WriteJSON(c.path, c)
+}
+
+// cacheKey normalizes a title so small variations share the same
+// entry.
+func cacheKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// movieCacheKey returns the key of the movie of the given activity.
+func movieCacheKey(h *netflix.WatchActivity) string {
+	if h.VideoID != "" {
+		return h.VideoID
+	}
+	return cacheKey(h.Title)
+}
+
+// missCacheKey returns the key of the given activity in the negative
+// cache.
+func missCacheKey(h *netflix.WatchActivity) string {
+	if h.IsShow {
+		return cacheKey(fmt.Sprintf("show:%s:%d:%s", h.Title, h.Season, h.EpisodeName))
+	}
+	return cacheKey("movie:" + h.Title)
+}
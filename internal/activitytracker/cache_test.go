@@ -0,0 +1,136 @@
+package activitytracker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "match_cache.json")
+	cache, err := NewMatchCache(path, 24*time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	episode := func(season, number int, title string) trakt.Episode {
+		return trakt.Episode{Season: season, Number: number, Title: title, Year: 2023, IDs: traktIDs(season*100 + number)}
+	}
+	season := func(number int, episodes ...trakt.Episode) trakt.Season {
+		return trakt.Season{Number: number, IDs: traktIDs(number), Episodes: episodes}
+	}
+	activity := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81698010",
+		RawTitle:    `Alice in Borderland: Season 2: "Episode 8"`,
+		Title:       "Alice in Borderland",
+		EpisodeName: "Episode 8",
+		IsShow:      true,
+		Season:      2,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+	show := trakt.Media{Title: "Alice in Borderland", Year: 2020, IDs: traktIDs(158473), Votes: 0, Homepage: "", Network: ""}
+
+	_, ok := cache.Show(activity.Title, now)
+	assert.False(t, ok)
+
+	// Seasons fetched one by one are merged
+	cache.PutShow(activity.Title, show, 1, []trakt.Season{season(1, episode(1, 8, "Episode 8"))}, false, now)
+	cache.PutShow(activity.Title, show, 1, []trakt.Season{season(2, episode(2, 8, "Episode 8"))}, false, now)
+	cached, ok := cache.Show("alice in borderland", now)
+	require.True(t, ok, "the title should be normalized")
+	require.Len(t, cached.Seasons, 2)
+	assert.False(t, cached.Complete)
+
+	got, _, ok := findCachedEpisode(activity, cached, testThresholds)
+	require.True(t, ok)
+	assert.Equal(t, 208, got.IDs.Trakt)
+
+	// Without a season, we can only trust the cache if it contains all
+	// the seasons
+	noSeason := *activity
+	noSeason.Season = 0
+	noSeason.EpisodeName = "Episode 9"
+	_, _, ok = findCachedEpisode(&noSeason, cached, testThresholds)
+	assert.False(t, ok)
+
+	cache.PutShow(activity.Title, show, 1, []trakt.Season{season(1, episode(1, 8, "Episode 8")), season(2, episode(2, 9, "Episode 9"))}, true, now)
+	got, _, ok = findCachedEpisode(&noSeason, cached, testThresholds)
+	require.True(t, ok)
+	assert.Equal(t, 209, got.IDs.Trakt)
+
+	// Misses
+	movie := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81614419",
+		RawTitle:    "Pain Hustlers",
+		Title:       "Pain Hustlers",
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+	cache.PutMiss(movie, errors.New("not found"), now)
+	msg, ok := cache.Miss(movie, now)
+	require.True(t, ok)
+	assert.Equal(t, "not found", msg)
+	_, ok = cache.Miss(movie, now.Add(time.Hour))
+	assert.False(t, ok, "the miss should have expired")
+
+	// Movies
+	cache.PutMovie(movie, trakt.Media{Title: "Pain Hustlers", Year: 2023, IDs: traktIDs(764041), Votes: 0, Homepage: "", Network: ""}, 1, now)
+	cachedMovie, ok := cache.Movie(movie, now)
+	require.True(t, ok)
+	assert.Equal(t, 764041, cachedMovie.Movie.IDs.Trakt)
+	cache.Invalidate(movie)
+	_, ok = cache.Movie(movie, now)
+	assert.False(t, ok, "the movie should have been invalidated")
+
+	// The expired entries are not persisted
+	require.NoError(t, cache.Write(now.Add(time.Hour)))
+	loaded, err := NewMatchCache(path, 24*time.Hour, time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Shows, 1)
+	assert.Empty(t, loaded.Misses)
+	_, ok = loaded.Show(activity.Title, now.Add(24*time.Hour))
+	assert.False(t, ok, "the show should have expired")
+}
+
+func TestMatchCacheDisabled(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewMatchCache(filepath.Join(t.TempDir(), "match_cache.json"), 0, 0)
+	require.NoError(t, err)
+
+	movie := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "",
+		RawTitle:    "Pain Hustlers",
+		Title:       "Pain Hustlers",
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+	now := time.Now()
+	cache.PutMovie(movie, trakt.Media{Title: "Pain Hustlers", Year: 2023, IDs: traktIDs(764041), Votes: 0, Homepage: "", Network: ""}, 1, now)
+	cache.PutMiss(movie, errors.New("not found"), now)
+	assert.Empty(t, cache.Movies)
+	assert.Empty(t, cache.Misses)
+}
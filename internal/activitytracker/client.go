@@ -5,13 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
 	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/overrides"
+	"github.com/Nivl/trakt-netflix/internal/pathutil"
 	"github.com/Nivl/trakt-netflix/internal/slack"
 	"github.com/Nivl/trakt-netflix/internal/trakt"
 	"golang.org/x/text/runes"
@@ -21,40 +26,156 @@ import (
 
 var wordStartingWithI = regexp.MustCompile(`(?m)(^|[\s\p{P}])i`)
 
-var errMultipleEpisodeMatches = errors.New("multiple matching episodes found")
+// maxBatchSize is the maximum number of items sent to Trakt at once.
+const maxBatchSize = 100
+
+var (
+	errMultipleEpisodeMatches = errors.New("multiple matching episodes found")
+	errNotFoundByTrakt        = errors.New("not found by Trakt")
+	errNoMatch                = errors.New("not found")
+	errCachedMiss             = errors.New("cached")
+)
+
+// Config contains the configuration of the activity tracker
+type Config struct {
+	// RelQueueFilePath is the path of the retry queue, relative to
+	// the config directory.
+	RelQueueFilePath string `env:"QUEUE_FILE_REL_PATH"`
+	// MaxAttempts is the number of times we try to sync an activity
+	// before giving up.
+	MaxAttempts int `env:"MAX_ATTEMPTS,default=5"`
+	// RetryDelay is the time to wait before the first retry. The delay
+	// doubles after each attempt.
+	RetryDelay time.Duration `env:"RETRY_DELAY,default=1h"`
+	// RelBackfillFilePath is the path of the file containing the
+	// progress of the backfill, relative to the config directory.
+	RelBackfillFilePath string `env:"BACKFILL_FILE_REL_PATH"`
+	// RelImportFilePath is the path of the file containing the
+	// progress of the imports, relative to the config directory.
+	RelImportFilePath string `env:"IMPORT_FILE_REL_PATH"`
+	// RelReviewFilePath is the path of the queue of the activities
+	// waiting to be reviewed by the user, relative to the config
+	// directory.
+	RelReviewFilePath string `env:"REVIEW_FILE_REL_PATH"`
+	// RelOverridesFilePath is the path of the file containing the
+	// user-defined mappings between Netflix titles and Trakt medias,
+	// relative to the config directory.
+	RelOverridesFilePath string `env:"OVERRIDES_FILE_REL_PATH"`
+	// RelCacheFilePath is the path of the cache containing the
+	// matches, relative to the config directory.
+	RelCacheFilePath string `env:"CACHE_FILE_REL_PATH"`
+	// CacheTTL is how long a match is cached. 0 disables the cache.
+	CacheTTL time.Duration `env:"CACHE_TTL,default=168h"`
+	// CacheMissTTL is how long an activity that couldn't be matched
+	// is cached. 0 disables the cache of the misses.
+	CacheMissTTL time.Duration `env:"CACHE_MISS_TTL,default=6h"`
+	// MatchThreshold is the minimum similarity, between 0 and 1, for
+	// a Trakt title to match a Netflix title.
+	MatchThreshold float64 `env:"MATCH_THRESHOLD,default=0.9"`
+	// LowConfidenceThreshold is the minimum similarity, between 0 and
+	// 1, for a title that doesn't match to be reported as a low
+	// confidence match instead of being reported as unmatched.
+	LowConfidenceThreshold float64 `env:"LOW_CONFIDENCE_THRESHOLD,default=0.75"`
+}
 
 // Client represents a client to interact with external services
 type Client struct {
 	traktClient   *trakt.Client
 	netflixClient *netflix.Client
 	slackClient   *slack.Client
+	queue         *Queue
+	reviews       *ReviewQueue
+	backfillPath  string
+	importPath    string
+	overrides     *overrides.Overrides
+	cache         *MatchCache
+	thresholds    thresholds
 }
 
 // New returns a new Client
-func New(traktClient *trakt.Client, netflixClient *netflix.Client, slackClient *slack.Client) *Client {
+func New(cfg Config, traktClient *trakt.Client, netflixClient *netflix.Client, slackClient *slack.Client) (*Client, error) {
+	if cfg.RelQueueFilePath == "" {
+		cfg.RelQueueFilePath = "retry_queue.json"
+	}
+	if cfg.RelBackfillFilePath == "" {
+		cfg.RelBackfillFilePath = "backfill.json"
+	}
+	if cfg.RelImportFilePath == "" {
+		cfg.RelImportFilePath = "import.json"
+	}
+	if cfg.RelReviewFilePath == "" {
+		cfg.RelReviewFilePath = "review_queue.json"
+	}
+	if cfg.RelOverridesFilePath == "" {
+		cfg.RelOverridesFilePath = "overrides.yaml"
+	}
+	if cfg.RelCacheFilePath == "" {
+		cfg.RelCacheFilePath = "match_cache.json"
+	}
+	if cfg.MatchThreshold <= 0 || cfg.MatchThreshold > 1 {
+		return nil, fmt.Errorf("invalid match threshold %v: must be greater than 0, and at most 1", cfg.MatchThreshold)
+	}
+	if cfg.LowConfidenceThreshold < 0 || cfg.LowConfidenceThreshold > cfg.MatchThreshold {
+		return nil, fmt.Errorf("invalid low confidence threshold %v: must be between 0 and the match threshold", cfg.LowConfidenceThreshold)
+	}
+
+	queue, err := NewQueue(filepath.Join(pathutil.ConfigDir(), cfg.RelQueueFilePath), cfg.MaxAttempts, cfg.RetryDelay)
+	if err != nil {
+		return nil, fmt.Errorf("create retry queue: %w", err)
+	}
+	reviews, err := NewReviewQueue(filepath.Join(pathutil.ConfigDir(), cfg.RelReviewFilePath))
+	if err != nil {
+		return nil, fmt.Errorf("create review queue: %w", err)
+	}
+	o, err := overrides.New(filepath.Join(pathutil.ConfigDir(), cfg.RelOverridesFilePath))
+	if err != nil {
+		return nil, fmt.Errorf("load overrides: %w", err)
+	}
+	cache, err := NewMatchCache(filepath.Join(pathutil.ConfigDir(), cfg.RelCacheFilePath), cfg.CacheTTL, cfg.CacheMissTTL)
+	if err != nil {
+		return nil, fmt.Errorf("create match cache: %w", err)
+	}
+
+	netflixClient.History.Overridden = o.Has
+
 	return &Client{
 		slackClient:   slackClient,
 		traktClient:   traktClient,
 		netflixClient: netflixClient,
-	}
+		queue:         queue,
+		reviews:       reviews,
+		backfillPath:  filepath.Join(pathutil.ConfigDir(), cfg.RelBackfillFilePath),
+		importPath:    filepath.Join(pathutil.ConfigDir(), cfg.RelImportFilePath),
+		overrides:     o,
+		cache:         cache,
+		thresholds: thresholds{
+			accept:        cfg.MatchThreshold,
+			lowConfidence: cfg.LowConfidenceThreshold,
+		},
+	}, nil
 }
 
 // Run fetches the viewing history from Netflix and marks it as
 // watched on Trakt
-func (c *Client) Run(ctx context.Context) error {
+func (c *Client) Run(ctx context.Context) (*Summary, error) {
 	if err := c.UpdateHistory(ctx); err != nil {
-		return err
+		return nil, err
 	}
-	c.MarkAsWatched(ctx)
-	if err := c.netflixClient.History.Write(); err != nil {
-		return fmt.Errorf("write history: %w", err)
+	summary, err := c.MarkAsWatched(ctx)
+	if err != nil {
+		return summary, err
 	}
-	return nil
+	if err = c.save(); err != nil {
+		return summary, err
+	}
+	return summary, nil
 }
 
 // UpdateHistory fetches the viewing history from Netflix and
 // updates the local history.
 func (c *Client) UpdateHistory(ctx context.Context) error {
+	// The titles are checked against the overrides while being parsed
+	c.reloadOverrides(ctx, c.slackClient)
 	err := c.netflixClient.UpdateHistory(ctx, c.slackClient)
 	if err != nil {
 		return fmt.Errorf("update history: %w", err)
@@ -62,104 +183,420 @@ func (c *Client) UpdateHistory(ctx context.Context) error {
 	return nil
 }
 
-// MarkAsWatched mark as watched all the provided media
-func (c *Client) MarkAsWatched(ctx context.Context) {
-	medias := new(trakt.MarkAsWatchedRequest)
-	for _, h := range c.netflixClient.History.NewActivity {
-		err := c.searchMedia(ctx, h, medias)
+// Reload replaces the history, the queues, and the cache by the ones
+// stored on disk, which may have been updated by another process
+// sharing the config directory.
+// The files recovered from their backup are still loaded, and are
+// returned as errors wrapping fileutil.ErrRecovered.
+func (c *Client) Reload() error {
+	loaders := []func() error{
+		func() error {
+			if err := c.netflixClient.History.Load(); err != nil {
+				return fmt.Errorf("load history: %w", err)
+			}
+			return nil
+		},
+		c.queue.load,
+		c.reviews.load,
+		c.cache.load,
+	}
+	recovered := []error{}
+	for _, load := range loaders {
+		err := load()
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fileutil.ErrRecovered) {
+			return err
+		}
+		recovered = append(recovered, err)
+	}
+	return errors.Join(recovered...)
+}
+
+// save writes the history and the queues to disk.
+func (c *Client) save() error {
+	if err := c.netflixClient.History.Write(); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	if err := c.queue.Write(); err != nil {
+		return fmt.Errorf("write retry queue: %w", err)
+	}
+	if err := c.reviews.Write(); err != nil {
+		return fmt.Errorf("write review queue: %w", err)
+	}
+	if err := c.cache.Write(time.Now()); err != nil {
+		return fmt.Errorf("write match cache: %w", err)
+	}
+	return nil
+}
+
+// enqueueNewActivity moves the new activity of the history to the queue.
+func (c *Client) enqueueNewActivity(now time.Time) {
+	history := c.netflixClient.History
+	c.removeCommitted()
+	for _, h := range history.NewActivity {
+		// The activity waiting for a review is only synced once the
+		// user picked its match
+		if c.reviews.Has(h.HistoryItem()) {
+			continue
+		}
+		c.queue.Add(h, now)
+	}
+	history.ClearNewActivity()
+}
+
+// MarkAsWatched marks as watched the activity of the queue that is due,
+// which includes all the new activity.
+// An activity is only committed to the history once Trakt confirmed it
+// got added. Everything else stays in the queue, to be retried.
+// The activity is sent to Trakt in batches, and the history and
+// the queue are saved after each batch so no progress is lost if the
+// process stops.
+// Activity ignored by the overrides is committed without being sent.
+// Activity that needs to be reviewed by the user is moved to the
+// review queue.
+func (c *Client) MarkAsWatched(ctx context.Context) (*Summary, error) {
+	now := time.Now()
+	summary := &Summary{Items: []SummaryItem{}}
+	c.reloadOverrides(ctx, c.slackClient)
+	c.enqueueNewActivity(now)
+
+	batch := make([]batchItem, 0, maxBatchSize)
+	for _, entry := range c.queue.Due(now) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		h := entry.Activity
+		match, err := c.findMatch(ctx, h)
+		if errors.Is(err, ErrIgnored) {
+			slog.InfoContext(ctx, "ignoring activity", "media", h.String())
+			c.netflixClient.History.Commit(h.HistoryItem())
+			c.queue.Remove(entry)
+			continue
+		}
+		if errors.Is(err, errCachedMiss) {
+			// Trakt wasn't searched, so it's not an attempt. The
+			// activity will be searched again once the miss expires.
+			slog.InfoContext(ctx, "skipping activity that recently failed to match", "media", h.String())
+			summary.add(h, ItemStatusUnmatched, 0, err.Error())
+			continue
+		}
 		if err != nil {
-			c.slackClient.SendMessage(ctx, "Trakt: Couldn't find: "+h.String()+"\nError: "+err.Error()+"\nPlease add manually.")
-			slog.ErrorContext(ctx, "media search failed", "isShow", h.IsShow, "media", h.String(), "error", err.Error())
+			// We don't want to count an attempt if we're stopping
+			if ctx.Err() != nil {
+				break
+			}
+			if review, ok := c.needsReview(ctx, h, err, now); ok {
+				c.queue.Remove(entry)
+				c.reviews.Add(review)
+				c.reportReview(ctx, review)
+				summary.add(h, ItemStatusNeedsReview, 0, err.Error())
+				continue
+			}
+			c.queue.Fail(entry, err, now)
+			c.reportFailure(ctx, entry)
+			var lowConfidence *lowConfidenceError
+			if errors.As(err, &lowConfidence) {
+				summary.add(h, ItemStatusLowConfidence, lowConfidence.Score, err.Error())
+				continue
+			}
+			summary.add(h, ItemStatusUnmatched, 0, err.Error())
 			continue
 		}
-		c.slackClient.SendMessage(ctx, "Adding to current watchlist batch: "+h.String())
+		batch = append(batch, batchItem{
+			entry: entry,
+			score: match.Score,
+			media: trakt.MarkAsWatched{
+				IDs:       match.IDs(),
+				WatchedAt: h.WatchedAt(now).Format(time.RFC3339),
+			},
+		})
+
+		msg := "Adding to current watchlist batch: " + h.String()
+		if entry.Attempts > 0 {
+			msg = fmt.Sprintf("Adding to current watchlist batch (attempt %d): %s", entry.Attempts+1, h.String())
+		}
+		if match.Score < 1 {
+			msg += fmt.Sprintf(" (matched %s, score %.2f)", match.String(), match.Score)
+		}
+		c.slackClient.SendMessage(ctx, msg)
+
+		if len(batch) == maxBatchSize {
+			if err = c.submit(ctx, batch, now, summary); err != nil {
+				return summary, err
+			}
+			batch = make([]batchItem, 0, maxBatchSize)
+		}
 
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	_, err := c.traktClient.MarkAsWatched(ctx, medias)
+	if len(batch) > 0 {
+		if err := c.submit(ctx, batch, now, summary); err != nil {
+			return summary, err
+		}
+	}
+
+	if len(summary.Items) > 0 {
+		c.slackClient.SendMessage(ctx, "Batch processed: "+summary.String())
+	}
+	return summary, nil
+}
+
+// submit marks a batch as watched on Trakt, and commits the activity
+// that got confirmed.
+// The history and the queue are saved once done.
+func (c *Client) submit(ctx context.Context, batch []batchItem, now time.Time, summary *Summary) error {
+	medias := &trakt.MarkAsWatchedRequest{
+		Movies:   []trakt.MarkAsWatched{},
+		Episodes: []trakt.MarkAsWatched{},
+	}
+	for _, item := range batch {
+		if item.entry.Activity.IsShow {
+			medias.Episodes = append(medias.Episodes, item.media)
+		} else {
+			medias.Movies = append(medias.Movies, item.media)
+		}
+	}
+
+	res, err := c.traktClient.MarkAsWatched(ctx, medias)
 	if err != nil {
+		// Nothing got confirmed, so everything will be retried on the
+		// next run. We don't count this as an attempt since the failure
+		// is not related to the activity itself.
+		for _, item := range batch {
+			item.entry.LastError = err.Error()
+			summary.add(item.entry.Activity, ItemStatusFailed, item.score, err.Error())
+		}
 		c.slackClient.SendMessage(ctx, "Trakt: Couldn't mark the batch as watched. Error: "+err.Error())
 		slog.ErrorContext(ctx, "failed to watch", "error", err.Error(), "medias", medias)
+		return c.save()
+	}
+
+	confirmed := 0
+	for _, item := range batch {
+		if isNotFound(res, item.entry.Activity.IsShow, item.media.IDs) {
+			// The media we matched doesn't exist on Trakt anymore, or
+			// we sent bad IDs. Either way it's an attempt that failed.
+			// The match may come from the cache, in which case it's
+			// outdated.
+			c.cache.Invalidate(item.entry.Activity)
+			err := fmt.Errorf("%w (trakt ID %d)", errNotFoundByTrakt, item.media.IDs.Trakt)
+			if !item.reviewed {
+				c.queue.Fail(item.entry, err, now)
+				c.reportFailure(ctx, item.entry)
+			}
+			summary.add(item.entry.Activity, ItemStatusNotFound, item.score, err.Error())
+			continue
+		}
+		c.netflixClient.History.Commit(item.entry.Activity.HistoryItem())
+		c.queue.Remove(item.entry)
+		summary.add(item.entry.Activity, ItemStatusAdded, item.score, "")
+		confirmed++
+	}
+
+	if added := res.Added.Movies + res.Added.Episodes; added != confirmed {
+		slog.WarnContext(ctx, "Trakt didn't add as many plays as expected", "added", added, "expected", confirmed)
+	}
+	return c.save()
+}
+
+// batchItem represents an entry of the queue that is part of the batch
+// sent to Trakt.
+type batchItem struct {
+	entry *QueueEntry
+	// media is what the entry got matched with
+	media trakt.MarkAsWatched
+	// score is the similarity between the titles of the entry and of
+	// the media.
+	score float64
+	// reviewed is true when the media got picked by the user, in which
+	// case the entry is not part of the retry queue.
+	reviewed bool
+}
+
+// isNotFound checks if Trakt reported the media with the given IDs as
+// not found.
+func isNotFound(res *trakt.MarkAsWatchedResponse, isShow bool, ids trakt.IDs) bool {
+	notFound := res.NotFound.Movies
+	if isShow {
+		notFound = res.NotFound.Episodes
+	}
+	for _, m := range notFound {
+		if m.IDs.Trakt == ids.Trakt {
+			return true
+		}
+	}
+	return false
+}
+
+// reportFailure reports an entry of the retry queue that just failed.
+func (c *Client) reportFailure(ctx context.Context, entry *QueueEntry) {
+	h := entry.Activity
+	slog.ErrorContext(ctx, "media search failed", "isShow", h.IsShow, "media", h.String(), "error", entry.LastError, "attempts", entry.Attempts)
+	if entry.Dead {
+		c.slackClient.SendMessage(ctx, fmt.Sprintf("Trakt: Couldn't find: %s\nError: %s\nGave up after %d attempts. Please add manually.", h.String(), entry.LastError, entry.Attempts))
 		return
 	}
-	c.slackClient.SendMessage(ctx, "Batch processed successfully")
-	c.netflixClient.History.ClearNewActivity()
+	c.slackClient.SendMessage(ctx, fmt.Sprintf("Trakt: Couldn't find: %s\nError: %s\nWill retry after %s.", h.String(), entry.LastError, entry.NextRetry.Format(time.RFC1123)))
 }
 
-// searchMedia tries to map a Netflix movie/episode to one on Trakt
-func (c *Client) searchMedia(ctx context.Context, h *netflix.WatchActivity, medias *trakt.MarkAsWatchedRequest) error {
-	now := time.Now().Format(time.RFC3339)
+// searchMedia tries to map a Netflix movie/episode to one on Trakt.
+// The matches and the misses are cached, so Trakt is only searched
+// when needed.
+func (c *Client) searchMedia(ctx context.Context, h *netflix.WatchActivity) (*Match, error) {
+	now := time.Now()
+	if msg, ok := c.cache.Miss(h, now); ok {
+		return nil, fmt.Errorf("%s (%w)", msg, errCachedMiss)
+	}
 
+	match, err := c.resolveMedia(ctx, h, now)
+	// Low confidence matches are not cached, so they keep being
+	// reported with their score until an override is added.
+	// Ambiguous matches are not cached either, so their candidates
+	// are available for the review.
+	if errors.Is(err, errNoMatch) {
+		c.cache.PutMiss(h, err, now)
+	}
+	return match, err
+}
+
+// resolveMedia returns the Trakt media matching the activity.
+func (c *Client) resolveMedia(ctx context.Context, h *netflix.WatchActivity, now time.Time) (*Match, error) {
 	if h.IsShow {
-		episode, err := c.findEpisode(ctx, h)
-		if err != nil {
-			return err
-		}
-		medias.Episodes = append(medias.Episodes, trakt.MarkAsWatched{
-			IDs:       episode.IDs,
-			WatchedAt: now,
-		})
-		return nil
+		return c.findEpisode(ctx, h, now)
+	}
+
+	if cached, ok := c.cache.Movie(h, now); ok {
+		return &Match{
+			Movie:   &cached.Movie,
+			Show:    nil,
+			Episode: nil,
+			Score:   cached.Score,
+		}, nil
 	}
 
 	response, err := c.traktClient.Search(ctx, trakt.SearchRequest{
-		Type:  trakt.SearchTypeMovie,
-		Query: h.SearchQuery(),
-		Show:  h.SearchShow(),
+		Type:   trakt.SearchTypeMovie,
+		Query:  h.SearchQuery(),
+		Show:   h.SearchShow(),
+		Fields: nil,
 	})
 	if err != nil {
-		return fmt.Errorf("searching Trakt (query=%q, activity=%s): %w", h.SearchQuery(), h.String(), err)
+		return nil, fmt.Errorf("searching Trakt (query=%q, activity=%s): %w", h.SearchQuery(), h.String(), err)
 	}
 
-	for i := range response.Results {
-		r := &response.Results[i]
-		if r.Type == trakt.SearchTypeMovie {
-			if !stringMatches(r.Movie.Title, h.Title) {
-				continue
-			}
-
-			medias.Movies = append(medias.Movies, trakt.MarkAsWatched{
-				IDs:       r.Movie.IDs,
-				WatchedAt: now,
-			})
-			return nil
+	movies := searchResults(response, trakt.SearchTypeMovie)
+	// Only the movies with the closest title are considered, so an
+	// exact match always wins over a similar title
+	candidates, score := bestScored(movies, func(m *trakt.Media) float64 {
+		return titleSimilarity(h.Title, m.Title)
+	})
+	if score < c.thresholds.accept {
+		aliasCandidates, aliasScore, err := c.findByAlias(ctx, h, trakt.SearchTypeMovie, h.SearchQuery(), movies)
+		if err != nil {
+			return nil, err
+		}
+		if aliasScore > score {
+			candidates, score = aliasCandidates, aliasScore
 		}
 	}
-	return errors.New("not found")
+	if len(candidates) > 0 {
+		if err = c.thresholds.check(candidates[0].Title, score); err != nil {
+			return nil, err
+		}
+	}
+	movie, err := pickCandidate(h, candidates)
+	if errors.Is(err, errAmbiguousMatch) {
+		matches := make([]*Match, 0, len(candidates))
+		for _, m := range candidates {
+			matches = append(matches, &Match{Movie: m, Show: nil, Episode: nil, Score: score})
+		}
+		return nil, &candidatesError{err: err, Candidates: matches}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.cache.PutMovie(h, *movie, score, now)
+	return &Match{
+		Movie:   movie,
+		Show:    nil,
+		Episode: nil,
+		Score:   score,
+	}, nil
 }
 
-func (c *Client) findEpisode(ctx context.Context, h *netflix.WatchActivity) (*trakt.Episode, error) {
+// findEpisode returns the show and the episode matching the activity.
+func (c *Client) findEpisode(ctx context.Context, h *netflix.WatchActivity, now time.Time) (*Match, error) {
+	if cached, ok := c.cache.Show(h.Title, now); ok {
+		if episode, score, ok := findCachedEpisode(h, cached, c.thresholds); ok {
+			return &Match{
+				Movie:   nil,
+				Show:    &cached.Show,
+				Episode: episode,
+				Score:   min(cached.Score, score),
+			}, nil
+		}
+	}
+
 	showSearch, err := c.traktClient.Search(ctx, trakt.SearchRequest{
-		Type:  trakt.SearchTypeShow,
-		Query: h.SearchShow(),
-		Show:  "",
+		Type:   trakt.SearchTypeShow,
+		Query:  h.SearchShow(),
+		Show:   "",
+		Fields: nil,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("searching Trakt show (show=%q, episode=%q, activity=%s): %w", h.SearchShow(), h.EpisodeName, h.String(), err)
 	}
 
-	lastMatchErr := errors.New("not found")
-	for i := range showSearch.Results {
-		r := &showSearch.Results[i]
-		if r.Type != trakt.SearchTypeShow || !stringMatches(r.Show.Title, h.Title) {
-			continue
+	results := searchResults(showSearch, trakt.SearchTypeShow)
+	// Only the shows with the closest title are considered, so an
+	// exact match always wins over a similar title
+	shows, showScore := bestScored(results, func(show *trakt.Media) float64 {
+		return activitySimilarity(h, show.Title)
+	})
+	if showScore < c.thresholds.accept {
+		aliasShows, aliasScore, err := c.findByAlias(ctx, h, trakt.SearchTypeShow, h.SearchShow(), results)
+		if err != nil {
+			return nil, err
+		}
+		if aliasScore > showScore {
+			shows, showScore = aliasShows, aliasScore
 		}
+	}
+	if len(shows) == 0 {
+		return nil, errNoMatch
+	}
+	if err = c.thresholds.check(shows[0].Title, showScore); err != nil {
+		return nil, err
+	}
+	// Shows with the same title rarely share episode names, so we
+	// look into all of them, starting with the best one.
+	if best, err := pickCandidate(h, shows); err == nil {
+		i := slices.Index(shows, best)
+		shows = slices.Insert(slices.Delete(shows, i, i+1), 0, best)
+	}
 
-		showID := showLookupID(r.Show)
+	var lastMatchErr error = errNoMatch
+	for _, show := range shows {
+		showID := showLookupID(*show)
 		if h.Season > 0 {
 			episodes, err := c.traktClient.GetSeasonEpisodes(ctx, showID, h.Season)
 			if err != nil {
 				return nil, fmt.Errorf("getting Trakt season episodes (show=%q, season=%d, activity=%s): %w", h.Title, h.Season, h.String(), err)
 			}
 
-			episode, err := findEpisodeInShowSeasons(h, []trakt.Season{{
+			seasons := []trakt.Season{{
 				Number:   h.Season,
 				IDs:      trakt.IDs{Trakt: 0, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil},
 				Episodes: episodes,
-			}})
+			}}
+			episode, score, err := matchEpisode(h, seasons, c.thresholds)
 			if err == nil {
-				return episode, nil
+				c.cache.PutShow(h.Title, *show, showScore, seasons, false, now)
+				return newEpisodeMatch(show, episode, min(showScore, score)), nil
 			}
 		}
 
@@ -168,17 +605,100 @@ func (c *Client) findEpisode(ctx context.Context, h *netflix.WatchActivity) (*tr
 			return nil, fmt.Errorf("getting Trakt show seasons (show=%q, activity=%s): %w", h.Title, h.String(), err)
 		}
 
-		episode, err := findEpisodeInShowSeasons(h, seasons)
+		episode, score, err := matchEpisode(h, seasons, c.thresholds)
 		if err == nil {
-			return episode, nil
+			c.cache.PutShow(h.Title, *show, showScore, seasons, true, now)
+			return newEpisodeMatch(show, episode, min(showScore, score)), nil
+		}
+		// Candidates are more useful to the user than a near-miss,
+		// which is more useful than a miss
+		var ambiguous *candidatesError
+		var lowConfidence *lowConfidenceError
+		switch {
+		case errors.As(lastMatchErr, &ambiguous):
+		case errors.As(err, &ambiguous):
+			lastMatchErr = withShow(err, show)
+		case !errors.As(lastMatchErr, &lowConfidence):
+			lastMatchErr = err
 		}
-		lastMatchErr = err
 	}
 
 	return nil, lastMatchErr
 }
 
-func findEpisodeInShowSeasons(h *netflix.WatchActivity, seasons []trakt.Season) (*trakt.Episode, error) {
+// newEpisodeMatch returns a Match for the given episode.
+func newEpisodeMatch(show *trakt.Media, episode *trakt.Episode, score float64) *Match {
+	return &Match{
+		Movie:   nil,
+		Show:    show,
+		Episode: episode,
+		Score:   score,
+	}
+}
+
+// findCachedEpisode looks for the episode of the activity in the
+// cached seasons of a show. It follows the same logic as findEpisode,
+// but only considers all the seasons if they are all cached.
+func findCachedEpisode(h *netflix.WatchActivity, cached *CachedShow, t thresholds) (*trakt.Episode, float64, bool) {
+	if h.Season > 0 {
+		if season, ok := cached.Season(h.Season); ok {
+			if episode, score, err := matchEpisode(h, []trakt.Season{*season}, t); err == nil {
+				return episode, score, true
+			}
+		}
+	}
+	if cached.Complete {
+		if episode, score, err := matchEpisode(h, cached.Seasons, t); err == nil {
+			return episode, score, true
+		}
+	}
+	return nil, 0, false
+}
+
+// matchEpisode returns the episode of the activity among the provided
+// seasons, along with the similarity between the names of the
+// episodes. The episode is looked up by name, then by the numbers
+// given by Netflix, then by the number contained in its name if the
+// name is generic (ex. "Episode 8"). Episodes found by their numbers
+// have a score of 1.
+func matchEpisode(h *netflix.WatchActivity, seasons []trakt.Season, t thresholds) (*trakt.Episode, float64, error) {
+	episode, score, err := findEpisodeInShowSeasons(h, seasons, t)
+	if err == nil {
+		return episode, score, nil
+	}
+	if episode, ok := findEpisodeByNumber(h, seasons); ok {
+		return episode, 1, nil
+	}
+	if episode, ok := findGenericEpisode(h, seasons); ok {
+		return episode, 1, nil
+	}
+	return nil, 0, err
+}
+
+// findEpisodeInShowSeasons returns the episode with the name the
+// closest to the name of the activity, along with the similarity
+// between the names.
+func findEpisodeInShowSeasons(h *netflix.WatchActivity, seasons []trakt.Season, t thresholds) (*trakt.Episode, float64, error) {
+	// Only the episodes with the closest name are considered, so an
+	// exact match always wins over a similar name
+	scores := make([][]float64, len(seasons))
+	bestScore := 0.0
+	closest := ""
+	for i := range seasons {
+		scores[i] = make([]float64, len(seasons[i].Episodes))
+		for j := range seasons[i].Episodes {
+			episode := &seasons[i].Episodes[j]
+			scores[i][j] = titleSimilarity(h.EpisodeName, episode.Title)
+			if scores[i][j] > bestScore {
+				bestScore = scores[i][j]
+				closest = episode.Title
+			}
+		}
+	}
+	if err := t.check(closest, bestScore); err != nil {
+		return nil, 0, err
+	}
+
 	var seasonMatches []*trakt.Episode
 	var allMatches []*trakt.Episode
 	var specialMatches []*trakt.Episode
@@ -187,7 +707,7 @@ func findEpisodeInShowSeasons(h *netflix.WatchActivity, seasons []trakt.Season)
 		season := &seasons[i]
 		for j := range season.Episodes {
 			episode := &season.Episodes[j]
-			if !stringMatches(episode.Title, h.EpisodeName) {
+			if scores[i][j] != bestScore {
 				continue
 			}
 
@@ -203,24 +723,35 @@ func findEpisodeInShowSeasons(h *netflix.WatchActivity, seasons []trakt.Season)
 
 	switch {
 	case len(seasonMatches) == 1:
-		return seasonMatches[0], nil
+		return seasonMatches[0], bestScore, nil
 	case len(seasonMatches) > 1:
-		return nil, errMultipleEpisodeMatches
+		return nil, 0, multipleEpisodesError(seasonMatches, bestScore)
 	case h.Season > 0 && len(allMatches) == 1:
-		return allMatches[0], nil
+		return allMatches[0], bestScore, nil
 	case h.Season > 0 && len(allMatches) > 1:
-		return nil, errMultipleEpisodeMatches
+		return nil, 0, multipleEpisodesError(allMatches, bestScore)
 	case len(allMatches) == 1:
-		return allMatches[0], nil
+		return allMatches[0], bestScore, nil
 	case len(specialMatches) == 1:
-		return specialMatches[0], nil
+		return specialMatches[0], bestScore, nil
 	case len(allMatches) > 1:
-		return nil, errMultipleEpisodeMatches
+		return nil, 0, multipleEpisodesError(allMatches, bestScore)
 	default:
-		return nil, errors.New("not found")
+		return nil, 0, errNoMatch
+	}
+}
+
+// multipleEpisodesError returns an error containing the episodes as
+// candidates. Their show needs to be set using withShow.
+func multipleEpisodesError(episodes []*trakt.Episode, score float64) error {
+	candidates := make([]*Match, 0, len(episodes))
+	for _, e := range episodes {
+		candidates = append(candidates, newEpisodeMatch(nil, e, score))
 	}
+	return &candidatesError{err: errMultipleEpisodeMatches, Candidates: candidates}
 }
 
+// showLookupID returns the ID to use to query the endpoints of a media.
 func showLookupID(show trakt.Media) string {
 	if show.IDs.Slug != nil && *show.IDs.Slug != "" {
 		return *show.IDs.Slug
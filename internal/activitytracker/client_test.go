@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Nivl/trakt-netflix/internal/mocks"
 	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/overrides"
+	"github.com/Nivl/trakt-netflix/internal/pathutil"
+	"github.com/Nivl/trakt-netflix/internal/secret"
 	"github.com/Nivl/trakt-netflix/internal/trakt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,81 +40,104 @@ func TestFetchHistory(t *testing.T) {
 
 	netflixClient := &netflix.Client{
 		History: &netflix.History{
-			ItemsSearch: make(map[string]struct{}),
-			Items:       []string{},
-			NewActivity: []*netflix.WatchActivity{},
+			ItemsSearch:    make(map[string]struct{}),
+			Items:          []netflix.HistoryItem{},
+			NewActivity:    []*netflix.WatchActivity{},
+			TrackRewatches: true,
+			Locale:         "",
+			Overridden:     nil,
 		},
 		Cookie:           "cookie",
 		WatchActivityURL: "https://www.netflix.com/viewingactivity",
+		ShaktiURL:        "",
 		HTTP:             Doer,
+		Location:         time.UTC,
+		DateLayout:       "",
 	}
 
-	var traktCfg trakt.ClientConfig
-	traktClient, err := trakt.NewClient(traktCfg)
-	require.NoError(t, err)
-
-	c := New(traktClient, netflixClient, nil)
-	require.NoError(t, err)
+	c := newTestClient(t, http.NotFoundHandler())
+	c.netflixClient = netflixClient
 
 	err = c.UpdateHistory(t.Context())
 	require.NoError(t, err)
+	assert.Equal(t, "en-US", netflixClient.History.Locale, "the locale of the page should be kept")
 
 	testCases := []struct {
 		entry   string
+		videoID string
+		date    time.Time
 		name    string
 		episode string
 		isShow  bool
 	}{
 		{
 			entry:   `Ali Wong: Hard Knock Wife`,
+			videoID: "80186940",
+			date:    time.Date(2023, time.November, 17, 0, 0, 0, 0, time.UTC),
 			name:    "Ali Wong: Hard Knock Wife",
 			episode: "",
 			isShow:  false,
 		},
 		{
 			entry:   `Scott Pilgrim Takes Off: Scott Pilgrim Takes Off: "Whatever"`,
+			videoID: "81643742",
+			date:    time.Date(2023, time.November, 17, 0, 0, 0, 0, time.UTC),
 			name:    "Scott Pilgrim Takes Off",
 			episode: "Whatever",
 			isShow:  true,
 		},
 		{
 			entry:   `Pain Hustlers`,
+			videoID: "81614419",
+			date:    time.Date(2023, time.November, 22, 0, 0, 0, 0, time.UTC),
 			name:    "Pain Hustlers",
 			episode: "",
 			isShow:  false,
 		},
 		{
 			entry:   `Goedam: Collection: "Threshold"`,
+			videoID: "81307056",
+			date:    time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
 			name:    "Goedam",
 			episode: "Threshold",
 			isShow:  true,
 		},
 		{
 			entry:   `Strong Girl Nam-soon: Limited Series: "Light and Shadow of Gangnam"`,
+			videoID: "81651547",
+			date:    time.Date(2023, time.November, 10, 0, 0, 0, 0, time.UTC),
 			name:    "Strong Girl Nam-soon",
 			episode: "Light and Shadow of Gangnam",
 			isShow:  true,
 		},
 		{
 			entry:   `Alice in Borderland: Season 2: "Episode 8"`,
+			videoID: "81698010",
+			date:    time.Date(2023, time.November, 10, 0, 0, 0, 0, time.UTC),
 			name:    "Alice in Borderland",
 			episode: "Episode 8",
 			isShow:  true,
 		},
 		{
 			entry:   `Squid Game: The Challenge: Squid Game: The Challenge: "Nowhere To Hide"`,
+			videoID: "81622700",
+			date:    time.Date(2023, time.November, 24, 0, 0, 0, 0, time.UTC),
 			name:    "Squid Game: The Challenge",
 			episode: "Nowhere To Hide",
 			isShow:  true,
 		},
 		{
 			entry:   `That '90s Show: Part 2: "Friends in Low Places"`,
+			videoID: "81601360",
+			date:    time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
 			name:    "That '90s Show",
 			episode: "Friends in Low Places",
 			isShow:  true,
 		},
 		{
 			entry:   `Slasher: The Executioner: "Soon Your Own Eyes Will See"`,
+			videoID: "81677518",
+			date:    time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
 			name:    "Slasher",
 			episode: "Soon Your Own Eyes Will See",
 			isShow:  true,
@@ -126,9 +154,14 @@ func TestFetchHistory(t *testing.T) {
 			assert.Equal(t, tc.episode, h[i].EpisodeName)
 			assert.Equal(t, tc.name, h[i].Title)
 			assert.Equal(t, tc.isShow, h[i].IsShow)
+			assert.Equal(t, tc.date, h[i].Date)
+
+			assert.Equal(t, tc.videoID, h[i].VideoID)
+			assert.Equal(t, tc.entry, h[i].RawTitle)
 
-			item := history.Items
-			assert.Equal(t, tc.entry, item[i])
+			// The activity only gets added to the history once
+			// it's been synced
+			assert.Empty(t, history.Items)
 		})
 	}
 }
@@ -136,18 +169,29 @@ func TestFetchHistory(t *testing.T) {
 func TestFetchHistoryWithExistingData(t *testing.T) {
 	t.Parallel()
 
-	show1 := `Scott Pilgrim Takes Off: Scott Pilgrim Takes Off: "Whatever"`
-	show2 := `Ali Wong: Hard Knock Wife`
+	show1 := netflix.HistoryItem{
+		VideoID: "81643742",
+		Title:   `Scott Pilgrim Takes Off: Scott Pilgrim Takes Off: "Whatever"`,
+		Date:    time.Date(2023, time.November, 17, 0, 0, 0, 0, time.UTC),
+	}
+	show2 := netflix.HistoryItem{
+		VideoID: "80186940",
+		Title:   `Ali Wong: Hard Knock Wife`,
+		Date:    time.Date(2023, time.November, 17, 0, 0, 0, 0, time.UTC),
+	}
 	history := &netflix.History{
-		Items: []string{
+		Items: []netflix.HistoryItem{
 			show1,
 			show2,
 		},
 		ItemsSearch: map[string]struct{}{
-			show1: {},
-			show2: {},
+			show1.Key(): {},
+			show2.Key(): {},
 		},
-		NewActivity: []*netflix.WatchActivity{},
+		NewActivity:    []*netflix.WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
 	}
 
 	data, err := os.ReadFile(filepath.Join("testdata", "netflix.html"))
@@ -168,15 +212,14 @@ func TestFetchHistoryWithExistingData(t *testing.T) {
 		History:          history,
 		Cookie:           "cookie",
 		WatchActivityURL: "https://www.netflix.com/viewingactivity",
+		ShaktiURL:        "",
 		HTTP:             Doer,
+		Location:         time.UTC,
+		DateLayout:       "",
 	}
 
-	var traktCfg trakt.ClientConfig
-	traktClient, err := trakt.NewClient(traktCfg)
-	require.NoError(t, err)
-
-	c := New(traktClient, netflixClient, nil)
-	require.NoError(t, err)
+	c := newTestClient(t, http.NotFoundHandler())
+	c.netflixClient = netflixClient
 
 	err = c.UpdateHistory(t.Context())
 	require.NoError(t, err)
@@ -257,11 +300,17 @@ func TestFindEpisodeInShowSeasons(t *testing.T) {
 		{
 			name: "prefers requested season when episode titles repeat",
 			activity: &netflix.WatchActivity{
-				Date:        "",
+				Date:        time.Time{},
+				VideoID:     "",
+				RawTitle:    "",
 				Title:       "Search Party",
 				EpisodeName: "Episode 1",
 				IsShow:      true,
 				Season:      2,
+				IsRewatch:   false,
+				Guessed:     false,
+				Metadata:    nil,
+				Year:        0,
 			},
 			seasons: []trakt.Season{
 				{
@@ -285,11 +334,17 @@ func TestFindEpisodeInShowSeasons(t *testing.T) {
 		{
 			name: "season zero ignores season but still accepts a unique best title match",
 			activity: &netflix.WatchActivity{
-				Date:        "",
+				Date:        time.Time{},
+				VideoID:     "",
+				RawTitle:    "",
 				Title:       "Arrested Development",
 				EpisodeName: "Season 4 Remix: A Couple-A New Starts",
 				IsShow:      true,
 				Season:      0,
+				IsRewatch:   false,
+				Guessed:     false,
+				Metadata:    nil,
+				Year:        0,
 			},
 			seasons: []trakt.Season{
 				{
@@ -313,11 +368,17 @@ func TestFindEpisodeInShowSeasons(t *testing.T) {
 		{
 			name: "returns ambiguous when season is unknown and title repeats",
 			activity: &netflix.WatchActivity{
-				Date:        "",
+				Date:        time.Time{},
+				VideoID:     "",
+				RawTitle:    "",
 				Title:       "Some Show",
 				EpisodeName: "Episode 1",
 				IsShow:      true,
 				Season:      0,
+				IsRewatch:   false,
+				Guessed:     false,
+				Metadata:    nil,
+				Year:        0,
 			},
 			seasons: []trakt.Season{
 				{
@@ -344,7 +405,7 @@ func TestFindEpisodeInShowSeasons(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			episode, err := findEpisodeInShowSeasons(tc.activity, tc.seasons)
+			episode, _, err := findEpisodeInShowSeasons(tc.activity, tc.seasons, testThresholds)
 			if tc.wantErr != "" {
 				require.Error(t, err)
 				require.EqualError(t, err, tc.wantErr)
@@ -427,3 +488,215 @@ func TestStringMatches(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNotFound(t *testing.T) {
+	t.Parallel()
+
+	res := &trakt.MarkAsWatchedResponse{}
+	res.NotFound.Episodes = append(res.NotFound.Episodes, struct {
+		IDs trakt.IDs `json:"ids"`
+	}{IDs: trakt.IDs{Trakt: 1001, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}})
+
+	assert.True(t, isNotFound(res, true, trakt.IDs{Trakt: 1001, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}))
+	assert.False(t, isNotFound(res, true, trakt.IDs{Trakt: 1002, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}))
+	assert.False(t, isNotFound(res, false, trakt.IDs{Trakt: 1001, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}), "movies and episodes have their own IDs")
+}
+
+// newTestClient returns a Client that stores its files in a temporary
+// directory, and that sends its Trakt requests to traktAPI.
+func newTestClient(t *testing.T, traktAPI http.Handler) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(traktAPI)
+	t.Cleanup(srv.Close)
+
+	// The auth file is relative to the config directory, which isn't
+	// the directory of the test
+	dir := t.TempDir()
+	authPath := filepath.Join(dir, "trakt_auth.json")
+	require.NoError(t, os.WriteFile(authPath, []byte(`{"access_token":"access-token","refresh_token":"refresh-token","created_at":1704153600}`), 0o600))
+	configDir, err := filepath.Abs(pathutil.ConfigDir())
+	require.NoError(t, err)
+	relAuthPath, err := filepath.Rel(configDir, authPath)
+	require.NoError(t, err)
+	traktClient, err := trakt.NewClient(trakt.ClientConfig{
+		ClientSecret:    secret.NewSecret(""),
+		ClientID:        "client-id",
+		RedirectURI:     "",
+		RelAuthFilePath: relAuthPath,
+		DryRun:          false,
+		BaseURL:         srv.URL,
+	})
+	require.NoError(t, err)
+	require.True(t, traktClient.IsAuthenticated())
+
+	history, err := netflix.NewHistory(filepath.Join(dir, "history"), true)
+	require.NoError(t, err)
+	queue, err := NewQueue(filepath.Join(dir, "retry_queue.json"), 5, time.Hour)
+	require.NoError(t, err)
+	reviews, err := NewReviewQueue(filepath.Join(dir, "review_queue.json"))
+	require.NoError(t, err)
+	o, err := overrides.New(filepath.Join(dir, "overrides.yaml"))
+	require.NoError(t, err)
+	cache, err := NewMatchCache(filepath.Join(dir, "match_cache.json"), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	return &Client{
+		traktClient: traktClient,
+		netflixClient: &netflix.Client{
+			HTTP:             nil,
+			History:          history,
+			WatchActivityURL: "",
+			ShaktiURL:        "",
+			Cookie:           "",
+			Location:         time.UTC,
+			DateLayout:       "",
+		},
+		slackClient:  nil,
+		queue:        queue,
+		reviews:      reviews,
+		backfillPath: filepath.Join(dir, "backfill.json"),
+		importPath:   filepath.Join(dir, "import.json"),
+		overrides:    o,
+		cache:        cache,
+		thresholds:   testThresholds,
+	}
+}
+
+// newMovieActivity returns the activity of a movie watched on
+// January 2, 2024.
+func newMovieActivity(title string) *netflix.WatchActivity {
+	return &netflix.WatchActivity{
+		Date:        time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		VideoID:     "",
+		RawTitle:    title,
+		Title:       title,
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		Year:        0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+	}
+}
+
+// newEpisodeActivity returns the activity of an episode watched on
+// January 2, 2024. A season of 0 means the season is unknown.
+func newEpisodeActivity(show string, season int, name string) *netflix.WatchActivity {
+	return &netflix.WatchActivity{
+		Date:        time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		VideoID:     "",
+		RawTitle:    show + `: "` + name + `"`,
+		Title:       show,
+		EpisodeName: name,
+		IsShow:      true,
+		Season:      season,
+		Year:        0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+	}
+}
+
+func TestMarkAsWatchedCachedMiss(t *testing.T) {
+	t.Parallel()
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Trakt should not be searched, got %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	activity := newMovieActivity("Some Movie")
+	now := time.Now()
+	c.queue.Add(activity, now)
+	c.cache.PutMiss(activity, errNoMatch, now)
+
+	for range 3 {
+		summary, err := c.MarkAsWatched(t.Context())
+		require.NoError(t, err)
+		require.Len(t, summary.Items, 1)
+		assert.Equal(t, ItemStatusUnmatched, summary.Items[0].Status)
+		assert.False(t, summary.Complete(), "the activity is still pending")
+	}
+
+	require.Len(t, c.queue.Entries, 1)
+	entry := c.queue.Entries[0]
+	assert.Zero(t, entry.Attempts, "a cached miss should not count as an attempt")
+	assert.False(t, entry.Dead)
+	assert.Len(t, c.queue.Due(time.Now()), 1, "the activity should be searched once the miss expires")
+}
+
+func TestMarkAsWatchedSubmit(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc           string
+		status         int
+		response       string
+		expectedStatus ItemStatus
+		committed      bool
+		attempts       int
+	}{
+		{
+			desc:           "confirmed",
+			status:         http.StatusCreated,
+			response:       `{"added":{"movies":1}}`,
+			expectedStatus: ItemStatusAdded,
+			committed:      true,
+			attempts:       0,
+		},
+		{
+			desc:           "not found",
+			status:         http.StatusCreated,
+			response:       `{"added":{},"not_found":{"movies":[{"ids":{"trakt":42}}]}}`,
+			expectedStatus: ItemStatusNotFound,
+			committed:      false,
+			attempts:       1,
+		},
+		{
+			desc:           "whole batch failed",
+			status:         http.StatusInternalServerError,
+			response:       `{}`,
+			expectedStatus: ItemStatusFailed,
+			committed:      false,
+			attempts:       0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /search/movie", func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, `[{"type":"movie","score":100,"movie":{"title":"Some Movie","year":2024,"ids":{"trakt":42}}}]`)
+			})
+			mux.HandleFunc("POST /sync/history", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"movies":[{"ids":{"trakt":42},"watched_at":"2024-01-02T00:00:00Z"}],"episodes":[]}`, string(body))
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.response)
+			})
+			c := newTestClient(t, mux)
+
+			activity := newMovieActivity("Some Movie")
+			c.netflixClient.History.NewActivity = []*netflix.WatchActivity{activity}
+
+			summary, err := c.MarkAsWatched(t.Context())
+			require.NoError(t, err)
+			require.Len(t, summary.Items, 1)
+			assert.Equal(t, tc.expectedStatus, summary.Items[0].Status)
+
+			// Everything should have been saved
+			require.NoError(t, c.Reload())
+			assert.Equal(t, tc.committed, c.netflixClient.History.Has(activity.HistoryItem()))
+			entry, pending := c.queue.Find(activity.HistoryItem().Key())
+			require.Equal(t, !tc.committed, pending, "only the confirmed activity should leave the queue")
+			if pending {
+				assert.Equal(t, tc.attempts, entry.Attempts)
+				assert.NotEmpty(t, entry.LastError)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package activitytracker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// popularityRatio is how many more votes than the others a candidate
+// needs to be picked based on its popularity alone.
+const popularityRatio = 10
+
+var errAmbiguousMatch = errors.New("multiple medias match")
+
+// pickCandidate returns the media that matches the activity the best,
+// among medias that all have a matching title (ex. remakes, or
+// different movies with the same name).
+//
+// The candidates are narrowed down using, in order:
+//   - The release date. A media cannot be watched before its release.
+//   - The release year given by Netflix, when known.
+//   - Whether the media is a Netflix production, since it got watched
+//     on Netflix.
+//   - The popularity, if a candidate is way more popular than the
+//     others.
+//
+// errAmbiguousMatch is returned if there are still multiple candidates.
+func pickCandidate(h *netflix.WatchActivity, candidates []*trakt.Media) (*trakt.Media, error) {
+	if len(candidates) == 0 {
+		return nil, errNoMatch
+	}
+
+	filters := []func(*trakt.Media) bool{
+		func(m *trakt.Media) bool {
+			return h.Date.IsZero() || m.Year == 0 || m.Year <= h.Date.Year()
+		},
+		func(m *trakt.Media) bool {
+			return h.Year > 0 && m.Year == h.Year
+		},
+		func(m *trakt.Media) bool {
+			return h.Year > 0 && m.Year >= h.Year-1 && m.Year <= h.Year+1
+		},
+		func(m *trakt.Media) bool {
+			return h.VideoID != "" && strings.Contains(m.Homepage, "netflix.com/title/"+h.VideoID)
+		},
+		isNetflixProduction,
+	}
+	for _, keep := range filters {
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+		candidates = narrow(candidates, keep)
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	// The first candidate is the most popular
+	candidates = slices.Clone(candidates)
+	slices.SortStableFunc(candidates, func(a, b *trakt.Media) int {
+		return b.Votes - a.Votes
+	})
+	if candidates[0].Votes >= popularityRatio*max(candidates[1].Votes, 1) {
+		return candidates[0], nil
+	}
+
+	names := make([]string, 0, len(candidates))
+	for _, m := range candidates {
+		names = append(names, fmt.Sprintf("%s (%d)", slug(m), m.Year))
+	}
+	return nil, fmt.Errorf("%w: %s. Add an override to pick one", errAmbiguousMatch, strings.Join(names, ", "))
+}
+
+// narrow returns the candidates for which keep returns true.
+// All the candidates are returned if none of them are kept, since
+// it means the criteria cannot be used to tell them apart.
+func narrow(candidates []*trakt.Media, keep func(*trakt.Media) bool) []*trakt.Media {
+	kept := make([]*trakt.Media, 0, len(candidates))
+	for _, m := range candidates {
+		if keep(m) {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == 0 {
+		return candidates
+	}
+	return kept
+}
+
+// isNetflixProduction returns whether the media has been produced or
+// aired by Netflix.
+func isNetflixProduction(m *trakt.Media) bool {
+	return strings.EqualFold(m.Network, "Netflix") || strings.Contains(m.Homepage, "netflix.com")
+}
@@ -0,0 +1,116 @@
+package activitytracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickCandidate(t *testing.T) {
+	t.Parallel()
+
+	rebecca1940 := &trakt.Media{Title: "Rebecca", Year: 1940, IDs: traktIDs(1940), Votes: 2000, Homepage: "", Network: ""}
+	rebecca2020 := &trakt.Media{Title: "Rebecca", Year: 2020, IDs: traktIDs(2020), Votes: 1500, Homepage: "https://www.netflix.com/title/80994584", Network: ""}
+	rebecca2025 := &trakt.Media{Title: "Rebecca", Year: 2025, IDs: traktIDs(2025), Votes: 0, Homepage: "", Network: ""}
+	rebecca1997 := &trakt.Media{Title: "Rebecca", Year: 1997, IDs: traktIDs(1997), Votes: 100, Homepage: "", Network: ""}
+	rebecca1962 := &trakt.Media{Title: "Rebecca", Year: 1962, IDs: traktIDs(1962), Votes: 1200, Homepage: "", Network: ""}
+
+	activity := func(videoID string, year int, date time.Time) *netflix.WatchActivity {
+		a := newMovieActivity("Rebecca")
+		a.VideoID, a.Year, a.Date = videoID, year, date
+		return a
+	}
+	watchedOn := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		activity   *netflix.WatchActivity
+		candidates []*trakt.Media
+		want       *trakt.Media
+		wantErr    error
+	}{
+		{
+			name:       "no candidates",
+			activity:   activity("", 0, time.Time{}),
+			candidates: []*trakt.Media{},
+			want:       nil,
+			wantErr:    errNoMatch,
+		},
+		{
+			name:       "single candidate",
+			activity:   activity("", 0, time.Time{}),
+			candidates: []*trakt.Media{rebecca1940},
+			want:       rebecca1940,
+			wantErr:    nil,
+		},
+		{
+			name:       "released after the viewing",
+			activity:   activity("", 0, watchedOn),
+			candidates: []*trakt.Media{rebecca2025, rebecca1997},
+			want:       rebecca1997,
+			wantErr:    nil,
+		},
+		{
+			name:       "release year from Netflix",
+			activity:   activity("", 1940, watchedOn),
+			candidates: []*trakt.Media{rebecca2020, rebecca1940},
+			want:       rebecca1940,
+			wantErr:    nil,
+		},
+		{
+			name:       "release year off by one",
+			activity:   activity("", 1941, watchedOn),
+			candidates: []*trakt.Media{rebecca2020, rebecca1940},
+			want:       rebecca1940,
+			wantErr:    nil,
+		},
+		{
+			name:       "netflix production",
+			activity:   activity("", 0, watchedOn),
+			candidates: []*trakt.Media{rebecca1940, rebecca2020},
+			want:       rebecca2020,
+			wantErr:    nil,
+		},
+		{
+			name:       "netflix ID wins over the year",
+			activity:   activity("80994584", 2020, watchedOn),
+			candidates: []*trakt.Media{rebecca1940, rebecca2020},
+			want:       rebecca2020,
+			wantErr:    nil,
+		},
+		{
+			name:       "way more popular",
+			activity:   activity("", 0, watchedOn),
+			candidates: []*trakt.Media{rebecca1997, rebecca1940},
+			want:       rebecca1940,
+			wantErr:    nil,
+		},
+		{
+			name:       "ambiguous",
+			activity:   activity("", 0, watchedOn),
+			candidates: []*trakt.Media{rebecca1940, rebecca1962, rebecca2025},
+			want:       nil,
+			wantErr:    nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := pickCandidate(tc.activity, tc.candidates)
+			switch {
+			case tc.wantErr != nil:
+				require.ErrorIs(t, err, tc.wantErr)
+			case tc.want == nil:
+				require.ErrorIs(t, err, errAmbiguousMatch)
+			default:
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package activitytracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/slack"
+)
+
+// DryRunItem represents an activity that would be synced, and what it
+// got matched with on Trakt.
+type DryRunItem struct {
+	Activity *netflix.WatchActivity
+	// Match is nil if the activity couldn't be matched.
+	Match *Match
+	// Err is the reason why the activity couldn't be matched.
+	Err error
+}
+
+// DryRun fetches the viewing history from Netflix and matches the
+// activity that would be synced by Run, without marking anything as
+// watched and without writing anything to disk.
+// Nothing is sent to Slack either.
+func (c *Client) DryRun(ctx context.Context) ([]DryRunItem, error) {
+	// A nil Slack client only logs the messages
+	var reporter *slack.Client
+	c.reloadOverrides(ctx, reporter)
+	if err := c.netflixClient.UpdateHistory(ctx, reporter); err != nil {
+		return nil, fmt.Errorf("update history: %w", err)
+	}
+
+	history := c.netflixClient.History
+	activities := []*netflix.WatchActivity{}
+	for _, entry := range c.queue.Due(time.Now()) {
+		if !history.Has(entry.Activity.HistoryItem()) {
+			activities = append(activities, entry.Activity)
+		}
+	}
+	for _, h := range history.NewActivity {
+		if !c.queue.Has(h.HistoryItem()) {
+			activities = append(activities, h)
+		}
+	}
+	history.ClearNewActivity()
+
+	items := make([]DryRunItem, 0, len(activities))
+	for _, h := range activities {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+		match, err := c.findMatch(ctx, h)
+		items = append(items, DryRunItem{
+			Activity: h,
+			Match:    match,
+			Err:      err,
+		})
+	}
+	return items, nil
+}
+
+// PrintDryRun writes the result of a dry run to w, as a table.
+// The score is also displayed for the low confidence matches that got
+// rejected.
+func PrintDryRun(w io.Writer, items []DryRunItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NETFLIX TITLE\tPARSED\tTRAKT ID\tSLUG\tSEASON\tEPISODE\tSCORE\tERROR")
+	for _, item := range items {
+		h := item.Activity
+		title := h.RawTitle
+		if title == "" {
+			title = h.Title
+		}
+		if item.Match == nil {
+			score := ""
+			var lowConfidence *lowConfidenceError
+			if errors.As(item.Err, &lowConfidence) {
+				score = formatScore(lowConfidence.Score)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t\t\t\t\t%s\t%v\n", title, h.String(), score, item.Err)
+			continue
+		}
+
+		m := item.Match
+		season, episode := "", ""
+		if m.Episode != nil {
+			season = strconv.Itoa(m.Episode.Season)
+			episode = strconv.Itoa(m.Episode.Number)
+		}
+		media := m.Movie
+		if media == nil {
+			media = m.Show
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t\n", title, h.String(), m.IDs().Trakt, slug(media), season, episode, formatScore(m.Score))
+	}
+	return tw.Flush()
+}
+
+// formatScore returns the score of a match, as displayed to the user.
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 2, 64)
+}
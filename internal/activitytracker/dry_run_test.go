@@ -0,0 +1,106 @@
+package activitytracker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintDryRun(t *testing.T) {
+	t.Parallel()
+
+	movieSlug := "pain-hustlers-2023"
+	showSlug := "goedam"
+	movie := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81614419",
+		RawTitle:    "Pain Hustlers",
+		Title:       "Pain Hustlers",
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+	episode := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81307056",
+		RawTitle:    `Goedam: Collection: "Threshold"`,
+		Title:       "Goedam",
+		EpisodeName: "Threshold",
+		IsShow:      true,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+	unknown := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "",
+		RawTitle:    "Unknown",
+		Title:       "Unknown",
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+
+	items := []DryRunItem{
+		{
+			Activity: movie,
+			Match: &Match{
+				Movie:   &trakt.Media{Title: "Pain Hustlers", Year: 2023, IDs: trakt.IDs{Trakt: 1, Slug: &movieSlug, IMDB: nil, TMDB: nil, TVDB: nil}, Votes: 0, Homepage: "", Network: ""},
+				Show:    nil,
+				Episode: nil,
+				Score:   1,
+			},
+			Err: nil,
+		},
+		{
+			Activity: episode,
+			Match: &Match{
+				Movie:   nil,
+				Show:    &trakt.Media{Title: "Goedam", Year: 2020, IDs: trakt.IDs{Trakt: 2, Slug: &showSlug, IMDB: nil, TMDB: nil, TVDB: nil}, Votes: 0, Homepage: "", Network: ""},
+				Episode: &trakt.Episode{Season: 1, Number: 3, Title: "Threshold", Year: 2020, IDs: trakt.IDs{Trakt: 1003, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}},
+				Score:   0.93,
+			},
+			Err: nil,
+		},
+		{
+			Activity: unknown,
+			Match:    nil,
+			Err:      errors.New("no result"),
+		},
+		{
+			Activity: unknown,
+			Match:    nil,
+			Err:      &lowConfidenceError{Title: "Unknowns", Score: 0.8},
+		},
+	}
+
+	var out strings.Builder
+	require.NoError(t, PrintDryRun(&out, items))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, []string{"NETFLIX", "TITLE", "PARSED", "TRAKT", "ID", "SLUG", "SEASON", "EPISODE", "SCORE", "ERROR"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"Pain", "Hustlers", "Pain", "Hustlers", "1", "pain-hustlers-2023", "1.00"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"Goedam:", "Collection:", `"Threshold"`, "Goedam:", "Threshold", "1003", "goedam", "1", "3", "0.93"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"Unknown", "Unknown", "no", "result"}, strings.Fields(lines[3]))
+	assert.Equal(t, []string{"Unknown", "Unknown", "0.80", "low", "confidence", "match:", `"Unknowns"`, "(score", "0.80)"}, strings.Fields(lines[4]))
+
+	assert.Equal(t, "pain-hustlers-2023", items[0].Match.String())
+	assert.Equal(t, "goedam S01E03", items[1].Match.String())
+}
@@ -0,0 +1,91 @@
+package activitytracker
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// genericEpisodeNames contains the patterns of the episode names that
+// only contain the number of the episode (ex. "Episode 8"). The first
+// group is the number.
+var genericEpisodeNames = []*regexp.Regexp{
+	// English, French, Spanish, Portuguese, Italian, German, Dutch,
+	// Polish, Turkish, Swedish, Finnish, Czech, Hungarian, Russian
+	regexp.MustCompile(`(?i)^(?:episode|ep\.?|chapter|[ée]pisode|episodio|epis[óo]dio|cap[íi]tulo|capitolo|folge|aflevering|odcinek|b[öo]l[üu]m|avsnitt|jakso|epizoda|epiz[óo]d|серия|эпизод)\s*(\d+)$`),
+	// Japanese and Chinese (ex. "第8話", "第8集")
+	regexp.MustCompile(`^第\s*(\d+)\s*[話话集回]$`),
+	// Japanese (ex. "エピソード8")
+	regexp.MustCompile(`^エピソード\s*(\d+)$`),
+	// Korean (ex. "8화", "8회")
+	regexp.MustCompile(`^(\d+)\s*[화회]$`),
+}
+
+// genericEpisodeNumber returns the number of the episode if its name
+// is generic (ex. "Episode 8", "Ep. 8", "Chapter 8", "Épisode 8").
+func genericEpisodeNumber(name string) (int, bool) {
+	name = strings.TrimSpace(name)
+	for _, re := range genericEpisodeNames {
+		matches := re.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+		n, err := strconv.Atoi(matches[1])
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// findGenericEpisode returns the episode of the activity using the
+// number contained in its name, when the name is generic.
+// The number is looked up in the season of the activity. If the
+// season is unknown, the number is assumed to be an absolute number,
+// counting the episodes of all the regular seasons.
+func findGenericEpisode(h *netflix.WatchActivity, seasons []trakt.Season) (*trakt.Episode, bool) {
+	n, ok := genericEpisodeNumber(h.EpisodeName)
+	if !ok {
+		return nil, false
+	}
+
+	if h.Season > 0 {
+		for i := range seasons {
+			if seasons[i].Number != h.Season {
+				continue
+			}
+			for j := range seasons[i].Episodes {
+				if e := &seasons[i].Episodes[j]; e.Number == n {
+					return e, true
+				}
+			}
+		}
+		return nil, false
+	}
+
+	episodes := []*trakt.Episode{}
+	for i := range seasons {
+		// Specials are not part of the absolute numbering
+		if seasons[i].Number == 0 {
+			continue
+		}
+		for j := range seasons[i].Episodes {
+			episodes = append(episodes, &seasons[i].Episodes[j])
+		}
+	}
+	slices.SortFunc(episodes, func(a, b *trakt.Episode) int {
+		if a.Season != b.Season {
+			return a.Season - b.Season
+		}
+		return a.Number - b.Number
+	})
+	if n > len(episodes) {
+		return nil, false
+	}
+	return episodes[n-1], true
+}
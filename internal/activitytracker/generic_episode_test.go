@@ -0,0 +1,102 @@
+package activitytracker
+
+import (
+	"testing"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericEpisodeNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		want   int
+		wantOK bool
+	}{
+		{name: "Episode 8", want: 8, wantOK: true},
+		{name: "episode 12", want: 12, wantOK: true},
+		{name: "Ep. 3", want: 3, wantOK: true},
+		{name: "Ep 3", want: 3, wantOK: true},
+		{name: "Chapter 4", want: 4, wantOK: true},
+		{name: "Épisode 5", want: 5, wantOK: true},
+		{name: "Episodio 6", want: 6, wantOK: true},
+		{name: "Episódio 6", want: 6, wantOK: true},
+		{name: "Capítulo 7", want: 7, wantOK: true},
+		{name: "Folge 9", want: 9, wantOK: true},
+		{name: "Aflevering 10", want: 10, wantOK: true},
+		{name: "Bölüm 2", want: 2, wantOK: true},
+		{name: "Серия 2", want: 2, wantOK: true},
+		{name: "第8話", want: 8, wantOK: true},
+		{name: "第 8 集", want: 8, wantOK: true},
+		{name: "8화", want: 8, wantOK: true},
+		{name: "8회", want: 8, wantOK: true},
+		{name: "エピソード8", want: 8, wantOK: true},
+		{name: "1983", want: 0, wantOK: false},
+		{name: "Episode 0", want: 0, wantOK: false},
+		{name: "Threshold", want: 0, wantOK: false},
+		{name: "The Last Episode 2", want: 0, wantOK: false},
+		{name: "Episode 8: The End", want: 0, wantOK: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := genericEpisodeNumber(tc.name)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMatchEpisodeWithGenericName(t *testing.T) {
+	t.Parallel()
+
+	episode := func(season, number int, title string) trakt.Episode {
+		return trakt.Episode{Season: season, Number: number, Title: title, Year: 2023, IDs: traktIDs(season*100 + number)}
+	}
+	seasons := []trakt.Season{
+		{Number: 0, IDs: traktIDs(0), Episodes: []trakt.Episode{episode(0, 1, "Behind the scenes")}},
+		{Number: 1, IDs: traktIDs(1), Episodes: []trakt.Episode{episode(1, 1, "Pilot"), episode(1, 2, "The Game")}},
+		{Number: 2, IDs: traktIDs(2), Episodes: []trakt.Episode{episode(2, 1, "Back"), episode(2, 2, "The End")}},
+	}
+
+	testCases := []struct {
+		name      string
+		activity  *netflix.WatchActivity
+		wantTrakt int
+	}{
+		{
+			name:      "number in the season",
+			activity:  newEpisodeActivity("The Show", 2, "Episode 2"),
+			wantTrakt: 202,
+		},
+		{
+			name:      "absolute number without season",
+			activity:  newEpisodeActivity("The Show", 0, "Episode 3"),
+			wantTrakt: 201,
+		},
+		{
+			name:      "names are still preferred",
+			activity:  newEpisodeActivity("The Show", 0, "The Game"),
+			wantTrakt: 102,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, _, err := matchEpisode(tc.activity, seasons, testThresholds)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTrakt, got.IDs.Trakt)
+		})
+	}
+
+	_, _, err := matchEpisode(newEpisodeActivity("The Show", 0, "Episode 5"), seasons, testThresholds)
+	require.ErrorIs(t, err, errNoMatch, "there are only 4 regular episodes")
+	_, _, err = matchEpisode(newEpisodeActivity("The Show", 3, "Episode 1"), seasons, testThresholds)
+	require.ErrorIs(t, err, errNoMatch, "there's no season 3")
+}
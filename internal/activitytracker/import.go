@@ -0,0 +1,104 @@
+package activitytracker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+)
+
+// importCheckpoint keeps track of what has already been imported, so
+// an import can be resumed, or run again with a newer export.
+type importCheckpoint struct {
+	// ImportedUntil contains, for each source (ex. a Netflix profile),
+	// the date of the most recent item that got imported.
+	ImportedUntil map[string]time.Time `json:"importedUntil"`
+}
+
+// Import marks as watched on Trakt a list of items coming from an
+// external source, like Netflix's CSV export. Items must be sorted
+// from oldest to newest.
+//
+// Items that have already been imported from the same source are
+// skipped. Items are added to the retry queue before being synced, so
+// an interrupted import resumes where it stopped when called again.
+//
+// Items without a video ID that have already been seen on the viewing
+// activity page are skipped as well, using their title and their
+// viewing day.
+func (c *Client) Import(ctx context.Context, source string, items []netflix.HistoryItem) (*Summary, error) {
+	checkpoint := &importCheckpoint{ImportedUntil: map[string]time.Time{}}
+	if err := c.readCheckpoint(ctx, c.importPath, checkpoint); err != nil {
+		return nil, fmt.Errorf("load import checkpoint: %w", err)
+	}
+	if checkpoint.ImportedUntil == nil {
+		checkpoint.ImportedUntil = map[string]time.Time{}
+	}
+
+	importedUntil := checkpoint.ImportedUntil[source]
+	toImport := make([]netflix.HistoryItem, 0, len(items))
+	duplicates := 0
+	for _, item := range items {
+		if !item.Date.After(importedUntil) {
+			continue
+		}
+		if item.VideoID == "" && c.hasViewing(item) {
+			duplicates++
+			continue
+		}
+		toImport = append(toImport, item)
+	}
+	if duplicates > 0 {
+		slog.InfoContext(ctx, "skipping the items already synced from the viewing activity page", "count", duplicates)
+	}
+
+	if len(toImport) > 0 {
+		// The queue needs to be saved before the checkpoint, otherwise
+		// we could lose items
+		if err := c.enqueue(ctx, toImport); err != nil {
+			return nil, err
+		}
+		checkpoint.ImportedUntil[source] = toImport[len(toImport)-1].Date
+		if err := fileutil.WriteJSON(c.importPath, checkpoint); err != nil {
+			return nil, fmt.Errorf("write import checkpoint: %w", err)
+		}
+	}
+
+	summary, err := c.MarkAsWatched(ctx)
+	if err != nil {
+		return summary, err
+	}
+	if err = c.save(); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// hasViewing checks if the same title, watched on the same day, is
+// already in the history, or waiting to be synced or reviewed.
+func (c *Client) hasViewing(item netflix.HistoryItem) bool {
+	if c.netflixClient.History.HasViewing(item) {
+		return true
+	}
+	for _, entry := range c.queue.Entries {
+		if entry.Activity.HistoryItem().SameViewing(item) {
+			return true
+		}
+	}
+	for _, entry := range c.reviews.Entries {
+		if entry.Activity.HistoryItem().SameViewing(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindMatch returns the Trakt media matching the provided activity,
+// without marking it as watched.
+// ErrIgnored is returned if the overrides ignore the activity.
+func (c *Client) FindMatch(ctx context.Context, activity *netflix.WatchActivity) (*Match, error) {
+	return c.findMatch(ctx, activity)
+}
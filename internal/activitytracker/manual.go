@@ -0,0 +1,78 @@
+package activitytracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// ErrNotQueued is returned when an activity is neither in the retry
+// queue, nor in the review queue.
+var ErrNotQueued = errors.New("activity not found in the queues")
+
+// Pending returns the activities of the retry queue, including the
+// ones that won't be retried anymore.
+func (c *Client) Pending() []*QueueEntry {
+	return c.queue.Entries
+}
+
+// Retry makes the activity with the given key due right away, and
+// gives it a new set of attempts. The key is the one returned by
+// netflix.HistoryItem.Key.
+// The activity is retried during the next run.
+func (c *Client) Retry(key string, now time.Time) error {
+	entry, ok := c.queue.Find(key)
+	if !ok {
+		return ErrNotQueued
+	}
+	entry.Attempts = 0
+	entry.NextRetry = now
+	entry.Dead = false
+	if err := c.queue.Write(); err != nil {
+		return fmt.Errorf("write retry queue: %w", err)
+	}
+	return nil
+}
+
+// Assign marks the activity with the given key as watched on Trakt,
+// using the provided match instead of looking for one. The key is the
+// one returned by netflix.HistoryItem.Key.
+// The activity can be in the retry queue or in the review queue.
+func (c *Client) Assign(ctx context.Context, key string, match *Match) (*Summary, error) {
+	entry, ok := c.reviews.Find(key)
+	if !ok {
+		pending, ok := c.queue.Find(key)
+		if !ok {
+			return nil, ErrNotQueued
+		}
+		entry = newReviewEntry(pending.Activity, errors.New(pending.LastError), []*Match{}, time.Now())
+	}
+	return c.ApplyReviews(ctx, []ReviewDecision{{
+		Entry:    entry,
+		Match:    match,
+		Override: false,
+	}})
+}
+
+// NewManualMatch returns a Match pointing to the movie or the episode
+// with the given Trakt ID, as set by the user.
+func NewManualMatch(isEpisode bool, traktID int) *Match {
+	ids := traktIDs(traktID)
+	if isEpisode {
+		return &Match{
+			Movie:   nil,
+			Show:    nil,
+			Episode: &trakt.Episode{Season: 0, Number: 0, Title: "", Year: 0, IDs: ids},
+			Score:   1,
+		}
+	}
+	return &Match{
+		Movie:   &trakt.Media{Title: "", Year: 0, IDs: ids, Votes: 0, Homepage: "", Network: ""},
+		Show:    nil,
+		Episode: nil,
+		Score:   1,
+	}
+}
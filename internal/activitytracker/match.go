@@ -0,0 +1,50 @@
+package activitytracker
+
+import (
+	"fmt"
+
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// Match represents the media an activity got matched with on Trakt.
+type Match struct {
+	// Movie is set when the activity is a movie.
+	Movie *trakt.Media `json:"movie,omitempty"`
+	// Show is set when the activity is an episode.
+	Show *trakt.Media `json:"show,omitempty"`
+	// Episode is set when the activity is an episode.
+	Episode *trakt.Episode `json:"episode,omitempty"`
+	// Score is the similarity between the Netflix and Trakt titles,
+	// from 0 to 1. For episodes, it's the lowest score between the
+	// titles of the show and the names of the episode.
+	Score float64 `json:"score"`
+}
+
+// IDs returns the Trakt IDs of the movie or episode.
+func (m *Match) IDs() trakt.IDs {
+	if m.Episode != nil {
+		return m.Episode.IDs
+	}
+	return m.Movie.IDs
+}
+
+// String implements the Stringer interface.
+// Ex: "pain-hustlers-2023" or "alice-in-borderland S02E08"
+func (m *Match) String() string {
+	if m.Episode != nil {
+		return fmt.Sprintf("%s S%02dE%02d", slug(m.Show), m.Episode.Season, m.Episode.Number)
+	}
+	return slug(m.Movie)
+}
+
+// slug returns the slug of the media, or its title if it doesn't have
+// one.
+func slug(m *trakt.Media) string {
+	if m == nil {
+		return ""
+	}
+	if m.IDs.Slug != nil {
+		return *m.IDs.Slug
+	}
+	return m.Title
+}
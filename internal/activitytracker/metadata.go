@@ -0,0 +1,51 @@
+package activitytracker
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// addMetadata fetches the metadata of the activity from Netflix.
+// The metadata is stored in the activity, so it's only fetched once.
+// Failing to fetch the metadata is not an error, since the title
+// contains enough information most of the time.
+func (c *Client) addMetadata(ctx context.Context, h *netflix.WatchActivity) {
+	if h.Metadata != nil || h.VideoID == "" {
+		return
+	}
+	md, err := c.netflixClient.FetchMetadata(ctx, h.VideoID)
+	if err != nil {
+		slog.WarnContext(ctx, "couldn't fetch the metadata", "media", h.String(), "videoID", h.VideoID, "error", err.Error())
+		return
+	}
+	h.Metadata = md
+	if h.Year == 0 {
+		h.Year = md.Year
+	}
+}
+
+// findEpisodeByNumber returns the episode at the season and episode
+// numbers given by Netflix.
+// This is used when the episode couldn't be found using its name,
+// since the numbering of Netflix doesn't always match the one of
+// Trakt (ex. specials, seasons split in multiple parts, etc.).
+func findEpisodeByNumber(h *netflix.WatchActivity, seasons []trakt.Season) (*trakt.Episode, bool) {
+	md := h.Metadata
+	if md == nil || !md.IsShow || md.Season == 0 || md.Episode == 0 {
+		return nil, false
+	}
+	for i := range seasons {
+		if seasons[i].Number != md.Season {
+			continue
+		}
+		for j := range seasons[i].Episodes {
+			if e := &seasons[i].Episodes[j]; e.Number == md.Episode {
+				return e, true
+			}
+		}
+	}
+	return nil, false
+}
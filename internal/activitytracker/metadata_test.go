@@ -0,0 +1,59 @@
+package activitytracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchEpisodeUsesMetadata(t *testing.T) {
+	t.Parallel()
+
+	seasons := []trakt.Season{
+		{
+			Number: 2,
+			IDs:    traktIDs(2),
+			Episodes: []trakt.Episode{
+				{Season: 2, Number: 7, Title: "Dead End", Year: 2022, IDs: traktIDs(207)},
+				{Season: 2, Number: 8, Title: "Alice in Borderland", Year: 2022, IDs: traktIDs(208)},
+			},
+		},
+	}
+	h := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81482809",
+		RawTitle:    `Alice in Borderland: Season 2: "The Final Game"`,
+		Title:       "Alice in Borderland",
+		EpisodeName: "The Final Game",
+		IsShow:      true,
+		Season:      2,
+		Year:        0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+	}
+
+	_, _, err := matchEpisode(h, seasons, testThresholds)
+	require.ErrorIs(t, err, errNoMatch, "the episode names don't match")
+
+	h.Metadata = &netflix.Metadata{
+		IsShow:       true,
+		Title:        "Alice in Borderland",
+		EpisodeTitle: "The Final Game",
+		Season:       2,
+		Episode:      8,
+		Runtime:      55 * time.Minute,
+		Year:         2020,
+	}
+	episode, _, err := matchEpisode(h, seasons, testThresholds)
+	require.NoError(t, err)
+	assert.Equal(t, 208, episode.IDs.Trakt)
+
+	h.Metadata.Episode = 9
+	_, _, err = matchEpisode(h, seasons, testThresholds)
+	require.ErrorIs(t, err, errNoMatch)
+}
@@ -0,0 +1,115 @@
+package activitytracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/overrides"
+	"github.com/Nivl/trakt-netflix/internal/slack"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// ErrIgnored is returned when an activity is ignored by the overrides.
+var ErrIgnored = errors.New("ignored by the overrides")
+
+// reloadOverrides reloads the overrides file if it changed. If the
+// file is invalid, the previous overrides are kept and the user is
+// notified using the provided reporter.
+func (c *Client) reloadOverrides(ctx context.Context, reporter *slack.Client) {
+	if err := c.overrides.Reload(); err != nil {
+		slog.ErrorContext(ctx, "failed to reload the overrides", "error", err.Error())
+		reporter.SendMessage(ctx, "Couldn't reload the overrides, the previous ones will be used. Error: "+err.Error())
+	}
+}
+
+// findMatch returns the Trakt media matching the provided activity.
+// The overrides are consulted first, and Trakt is only searched if
+// none of them matches the activity.
+// ErrIgnored is returned if the activity should not be synced.
+func (c *Client) findMatch(ctx context.Context, h *netflix.WatchActivity) (*Match, error) {
+	o, ok := c.overrides.Find(h.RawTitle)
+	if !ok {
+		c.addMetadata(ctx, h)
+		return c.searchMedia(ctx, h)
+	}
+
+	switch {
+	case o.Ignore:
+		return nil, ErrIgnored
+	case o.Movie != 0:
+		return &Match{
+			Movie:   &trakt.Media{Title: h.Title, Year: 0, IDs: traktIDs(o.Movie), Votes: 0, Homepage: "", Network: ""},
+			Show:    nil,
+			Episode: nil,
+			Score:   1,
+		}, nil
+	}
+
+	show := &trakt.Media{Title: h.Title, Year: 0, IDs: traktIDs(o.Show), Votes: 0, Homepage: "", Network: ""}
+	episode, err := c.findOverriddenEpisode(ctx, h, o)
+	if err != nil {
+		return nil, fmt.Errorf("override (show=%d, activity=%s): %w", o.Show, h.String(), withShow(err, show))
+	}
+	return &Match{
+		Movie:   nil,
+		Show:    show,
+		Episode: episode,
+		Score:   1,
+	}, nil
+}
+
+// findOverriddenEpisode returns the episode an override points to.
+// If the override doesn't contain the episode number, the episode is
+// found using its name.
+func (c *Client) findOverriddenEpisode(ctx context.Context, h *netflix.WatchActivity, o *overrides.Override) (*trakt.Episode, error) {
+	if o.Episode != 0 && o.Season == nil {
+		return nil, errors.New("a season is required to find an episode by its number")
+	}
+	showID := strconv.Itoa(o.Show)
+
+	// We only want to look in the season of the override
+	activity := *h
+	activity.Season = 0
+	if o.Season != nil {
+		activity.Season = *o.Season
+	}
+
+	var seasons []trakt.Season
+	if o.Season != nil {
+		episodes, err := c.traktClient.GetSeasonEpisodes(ctx, showID, *o.Season)
+		if err != nil {
+			return nil, fmt.Errorf("getting Trakt season episodes (season=%d): %w", *o.Season, err)
+		}
+		seasons = []trakt.Season{{
+			Number:   *o.Season,
+			IDs:      traktIDs(0),
+			Episodes: episodes,
+		}}
+	} else {
+		var err error
+		seasons, err = c.traktClient.GetShowSeasons(ctx, showID, true)
+		if err != nil {
+			return nil, fmt.Errorf("getting Trakt show seasons: %w", err)
+		}
+	}
+
+	if o.Episode == 0 {
+		episode, _, err := matchEpisode(&activity, seasons, c.thresholds)
+		return episode, err
+	}
+	for i := range seasons[0].Episodes {
+		if e := &seasons[0].Episodes[i]; e.Number == o.Episode {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("episode %d of season %d not found", o.Episode, *o.Season)
+}
+
+// traktIDs returns IDs containing only the provided Trakt ID.
+func traktIDs(id int) trakt.IDs {
+	return trakt.IDs{Trakt: id, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}
+}
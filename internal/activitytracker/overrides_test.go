@@ -0,0 +1,37 @@
+package activitytracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nivl/trakt-netflix/internal/overrides"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMatchUsesOverrides(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+- title: Pain Hustlers
+  movie: 764041
+- pattern: '^Ali Wong'
+  ignore: true
+`), 0o600))
+	o, err := overrides.New(path)
+	require.NoError(t, err)
+
+	// The trakt client is nil, so any search would panic
+	c := new(Client)
+	c.overrides = o
+
+	match, err := c.findMatch(t.Context(), newMovieActivity("Pain Hustlers"))
+	require.NoError(t, err)
+	assert.Equal(t, 764041, match.IDs().Trakt)
+	assert.Equal(t, "Pain Hustlers", match.String())
+
+	_, err = c.findMatch(t.Context(), newMovieActivity("Ali Wong: Hard Knock Wife"))
+	require.ErrorIs(t, err, ErrIgnored)
+}
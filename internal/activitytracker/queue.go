@@ -0,0 +1,160 @@
+package activitytracker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+)
+
+// maxRetryDelay is the maximum amount of time to wait between two
+// attempts.
+const maxRetryDelay = 24 * time.Hour
+
+// QueueEntry represents an activity that hasn't been synced with Trakt
+// yet.
+type QueueEntry struct {
+	Activity *netflix.WatchActivity `json:"activity"`
+	// Attempts is the number of times we tried to sync the activity.
+	Attempts int `json:"attempts"`
+	// LastError is the error returned by the last attempt.
+	LastError string `json:"lastError,omitempty"`
+	// NextRetry is the time after which the activity can be retried.
+	NextRetry time.Time `json:"nextRetry"`
+	// Dead is true when the activity failed too many times, and won't
+	// be retried anymore.
+	Dead bool `json:"dead,omitempty"`
+}
+
+// Queue contains the activities that haven't been confirmed by Trakt
+// yet. This includes the new activities, and the ones that failed and
+// need to be retried.
+type Queue struct {
+	Entries []*QueueEntry `json:"entries"`
+
+	path        string
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// NewQueue creates a new Queue, and loads the initial data stored on
+// disk at the given path.
+// Entries are retried with an exponential backoff starting at
+// retryDelay, and are marked as dead after maxAttempts attempts.
+func NewQueue(path string, maxAttempts int, retryDelay time.Duration) (*Queue, error) {
+	if maxAttempts < 1 {
+		return nil, fmt.Errorf("invalid max attempts %d: must be at least 1", maxAttempts)
+	}
+	q := &Queue{
+		Entries:     []*QueueEntry{},
+		path:        path,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+	}
+	// The recovery of a file is reported when the files get reloaded
+	// before being used
+	if err := q.load(); err != nil && !errors.Is(err, fileutil.ErrRecovered) {
+		return nil, err
+	}
+	return q, nil
+}
+
+// load replaces the entries of the queue by the ones stored on disk.
+func (q *Queue) load() error {
+	q.Entries = []*QueueEntry{}
+	if err := fileutil.ReadJSON(q.path, q); err != nil {
+		return fmt.Errorf("load queue: %w", err)
+	}
+	return nil
+}
+
+// Add adds a new activity to the queue. The activity is due right away.
+// Noop if the activity is already in the queue.
+func (q *Queue) Add(activity *netflix.WatchActivity, now time.Time) {
+	if q.Has(activity.HistoryItem()) {
+		return
+	}
+
+	q.Entries = append(q.Entries, &QueueEntry{
+		Activity:  activity,
+		Attempts:  0,
+		LastError: "",
+		NextRetry: now,
+		Dead:      false,
+	})
+}
+
+// Has checks if the queue contains the activity of the given history
+// item.
+func (q *Queue) Has(item netflix.HistoryItem) bool {
+	_, ok := q.Find(item.Key())
+	return ok
+}
+
+// Find returns the entry of the activity with the given key, as
+// returned by netflix.HistoryItem.Key.
+func (q *Queue) Find(key string) (*QueueEntry, bool) {
+	for _, entry := range q.Entries {
+		if entry.Activity.HistoryItem().Key() == key {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// Fail records a failed attempt for the given entry, and schedules its
+// next retry.
+// The entry is marked as dead if it reached the maximum number of
+// attempts.
+func (q *Queue) Fail(entry *QueueEntry, err error, now time.Time) {
+	entry.Attempts++
+	entry.LastError = err.Error()
+	entry.NextRetry = now.Add(q.backoff(entry.Attempts))
+	if entry.Attempts >= q.maxAttempts {
+		entry.Dead = true
+	}
+}
+
+// backoff returns how long to wait before retrying an entry that
+// failed the given number of times.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.retryDelay
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// Due returns the entries that can be retried.
+func (q *Queue) Due(now time.Time) []*QueueEntry {
+	var due []*QueueEntry
+	for _, entry := range q.Entries {
+		if !entry.Dead && !entry.NextRetry.After(now) {
+			due = append(due, entry)
+		}
+	}
+	return due
+}
+
+// Remove removes the given entry from the queue.
+func (q *Queue) Remove(entry *QueueEntry) {
+	q.RemoveFunc(func(e *QueueEntry) bool {
+		return e == entry
+	})
+}
+
+// RemoveFunc removes all the entries for which del returns true.
+func (q *Queue) RemoveFunc(del func(*QueueEntry) bool) {
+	q.Entries = slices.DeleteFunc(q.Entries, del)
+}
+
+// Write saves the queue to disk.
+func (q *Queue) Write() error {
+	return fileutil.WriteJSON(q.path, q)
+}
@@ -0,0 +1,97 @@
+package activitytracker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQueueValidatesMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewQueue(filepath.Join(t.TempDir(), "retry_queue.json"), 0, time.Hour)
+	require.Error(t, err, "every entry would be dead after its first failure")
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "retry_queue.json")
+	q, err := NewQueue(path, 3, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Date(2024, time.September, 14, 12, 0, 0, 0, time.UTC)
+	activity := &netflix.WatchActivity{
+		Date:        time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		VideoID:     "81614419",
+		RawTitle:    "Pain Hustlers",
+		Title:       "Pain Hustlers",
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+
+	q.Add(activity, now)
+	q.Add(activity, now)
+	require.Len(t, q.Entries, 1, "activities should only be added once")
+	assert.True(t, q.Has(activity.HistoryItem()))
+	require.Len(t, q.Due(now), 1, "new activities should be due right away")
+
+	entry := q.Entries[0]
+	q.Fail(entry, errors.New("not found"), now)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.Equal(t, "not found", entry.LastError)
+	assert.Equal(t, now.Add(time.Hour), entry.NextRetry)
+	assert.False(t, entry.Dead)
+	assert.Empty(t, q.Due(now), "the entry should not be retried right away")
+
+	now = now.Add(time.Hour)
+	require.Len(t, q.Due(now), 1)
+	q.Fail(entry, errors.New("still not found"), now)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.Equal(t, now.Add(2*time.Hour), entry.NextRetry, "the delay should double")
+	assert.False(t, entry.Dead)
+
+	// The queue survives restarts
+	require.NoError(t, q.Write())
+	q, err = NewQueue(path, 3, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, q.Entries, 1)
+	entry = q.Entries[0]
+	assert.Equal(t, activity, entry.Activity)
+	assert.Equal(t, 2, entry.Attempts)
+
+	now = now.Add(2 * time.Hour)
+	require.Len(t, q.Due(now), 1)
+	q.Fail(entry, errors.New("still not found"), now)
+	assert.True(t, entry.Dead, "the entry should be dead after 3 attempts")
+	assert.Empty(t, q.Due(now.Add(maxRetryDelay)), "dead entries should not be retried")
+
+	q.Remove(entry)
+	assert.Empty(t, q.Entries)
+}
+
+func TestQueueBackoff(t *testing.T) {
+	t.Parallel()
+
+	q := &Queue{
+		Entries:     []*QueueEntry{},
+		path:        "",
+		maxAttempts: 10,
+		retryDelay:  time.Hour,
+	}
+	assert.Equal(t, time.Hour, q.backoff(1))
+	assert.Equal(t, 2*time.Hour, q.backoff(2))
+	assert.Equal(t, 16*time.Hour, q.backoff(5))
+	assert.Equal(t, maxRetryDelay, q.backoff(6))
+	assert.Equal(t, maxRetryDelay, q.backoff(100))
+}
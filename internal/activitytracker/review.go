@@ -0,0 +1,370 @@
+package activitytracker
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/overrides"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// maxReviewCandidates is the maximum number of candidates proposed to
+// the user for an activity.
+const maxReviewCandidates = 5
+
+// maxReviewShows is the maximum number of shows whose episodes are
+// proposed to the user for an activity.
+const maxReviewShows = 3
+
+// candidatesError is returned when an activity matches several medias,
+// and only the user can tell which one is the right one.
+type candidatesError struct {
+	err        error
+	Candidates []*Match
+}
+
+// Error implements the error interface.
+func (e *candidatesError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *candidatesError) Unwrap() error {
+	return e.err
+}
+
+// withShow sets the show of the candidates of err, if any, since the
+// episodes are looked for before knowing which show they belong to.
+func withShow(err error, show *trakt.Media) error {
+	var ambiguous *candidatesError
+	if errors.As(err, &ambiguous) {
+		for _, m := range ambiguous.Candidates {
+			m.Show = show
+		}
+	}
+	return err
+}
+
+// ReviewEntry represents an activity that couldn't be matched
+// automatically, and that needs to be reviewed by the user.
+type ReviewEntry struct {
+	Activity *netflix.WatchActivity `json:"activity"`
+	// Reason explains why the activity couldn't be matched.
+	Reason string `json:"reason"`
+	// Candidates are the Trakt medias the activity may be, the most
+	// likely first.
+	Candidates []*Match `json:"candidates"`
+	// AddedAt is when the activity got added to the review queue.
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// ReviewQueue contains the activities waiting for the user to pick
+// their match.
+type ReviewQueue struct {
+	Entries []*ReviewEntry `json:"entries"`
+
+	path string
+}
+
+// NewReviewQueue creates a new ReviewQueue, and loads the initial data
+// stored on disk at the given path.
+func NewReviewQueue(path string) (*ReviewQueue, error) {
+	q := &ReviewQueue{
+		Entries: []*ReviewEntry{},
+		path:    path,
+	}
+	// The recovery of a file is reported when the files get reloaded
+	// before being used
+	if err := q.load(); err != nil && !errors.Is(err, fileutil.ErrRecovered) {
+		return nil, err
+	}
+	return q, nil
+}
+
+// load replaces the entries of the queue by the ones stored on disk.
+func (q *ReviewQueue) load() error {
+	q.Entries = []*ReviewEntry{}
+	if err := fileutil.ReadJSON(q.path, q); err != nil {
+		return fmt.Errorf("load review queue: %w", err)
+	}
+	return nil
+}
+
+// Add adds an entry to the queue.
+// Noop if the activity is already in the queue.
+func (q *ReviewQueue) Add(entry *ReviewEntry) {
+	if q.Has(entry.Activity.HistoryItem()) {
+		return
+	}
+	q.Entries = append(q.Entries, entry)
+}
+
+// Has checks if the queue contains the activity of the given history
+// item.
+func (q *ReviewQueue) Has(item netflix.HistoryItem) bool {
+	_, ok := q.Find(item.Key())
+	return ok
+}
+
+// Find returns the entry of the activity with the given key, as
+// returned by netflix.HistoryItem.Key.
+func (q *ReviewQueue) Find(key string) (*ReviewEntry, bool) {
+	for _, entry := range q.Entries {
+		if entry.Activity.HistoryItem().Key() == key {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// RemoveFunc removes all the entries for which del returns true.
+func (q *ReviewQueue) RemoveFunc(del func(*ReviewEntry) bool) {
+	q.Entries = slices.DeleteFunc(q.Entries, del)
+}
+
+// Write saves the queue to disk.
+func (q *ReviewQueue) Write() error {
+	return fileutil.WriteJSON(q.path, q)
+}
+
+// ReviewDecision represents what the user decided to do with an entry
+// of the review queue.
+type ReviewDecision struct {
+	Entry *ReviewEntry
+	// Match is the candidate picked by the user. Nil to ignore the
+	// activity, in which case Override must be true.
+	Match *Match
+	// Override is true to write an override, so the activities with
+	// the same title are matched the same way in the future.
+	Override bool
+}
+
+// Reviews returns the activities waiting to be reviewed.
+func (c *Client) Reviews() []*ReviewEntry {
+	return c.reviews.Entries
+}
+
+// ApplyReviews applies the decisions of the user. The overrides are
+// written, and the picked matches are marked as watched on Trakt.
+// The activities are removed from the review queue once Trakt
+// confirmed them.
+func (c *Client) ApplyReviews(ctx context.Context, decisions []ReviewDecision) (*Summary, error) {
+	now := time.Now()
+	summary := &Summary{Items: []SummaryItem{}}
+	c.reloadOverrides(ctx, c.slackClient)
+
+	batch := make([]batchItem, 0, len(decisions))
+	for _, d := range decisions {
+		h := d.Entry.Activity
+		if d.Override {
+			if err := c.addOverride(h, d.Match); err != nil {
+				return summary, fmt.Errorf("add override (activity=%s): %w", h.String(), err)
+			}
+		}
+		if d.Match == nil {
+			slog.InfoContext(ctx, "ignoring activity", "media", h.String())
+			c.netflixClient.History.Commit(h.HistoryItem())
+			continue
+		}
+		// The user may have picked a movie for an activity that got
+		// parsed as an episode, or the other way around
+		activity := *h
+		activity.IsShow = d.Match.Episode != nil
+		batch = append(batch, batchItem{
+			// The entry is not part of the retry queue, it's only used
+			// to submit the activity
+			entry: &QueueEntry{
+				Activity:  &activity,
+				Attempts:  0,
+				LastError: "",
+				NextRetry: now,
+				Dead:      false,
+			},
+			score: d.Match.Score,
+			media: trakt.MarkAsWatched{
+				IDs:       d.Match.IDs(),
+				WatchedAt: h.WatchedAt(now).Format(time.RFC3339),
+			},
+			reviewed: true,
+		})
+	}
+
+	for items := range slices.Chunk(batch, maxBatchSize) {
+		if err := c.submit(ctx, items, now, summary); err != nil {
+			return summary, err
+		}
+	}
+	c.removeCommitted()
+	return summary, c.save()
+}
+
+// addOverride writes an override mapping the title of the activity to
+// the given match, or ignoring it if match is nil.
+// Noop if an override already exists for the title.
+func (c *Client) addOverride(h *netflix.WatchActivity, match *Match) error {
+	if _, ok := c.overrides.Find(h.RawTitle); ok {
+		return nil
+	}
+	return c.overrides.Add(newOverrideRule(h, match))
+}
+
+// newOverrideRule returns a rule mapping the title of the activity to
+// the given match, or ignoring it if match is nil.
+func newOverrideRule(h *netflix.WatchActivity, match *Match) *overrides.Rule {
+	rule := &overrides.Rule{
+		Title:   h.RawTitle,
+		Pattern: "",
+		Ignore:  false,
+		Movie:   0,
+		Show:    0,
+		Season:  nil,
+		Episode: 0,
+	}
+	switch {
+	case match == nil:
+		rule.Ignore = true
+	case match.Episode != nil:
+		season := match.Episode.Season
+		rule.Show = match.Show.IDs.Trakt
+		rule.Season = &season
+		rule.Episode = match.Episode.Number
+	default:
+		rule.Movie = match.Movie.IDs.Trakt
+	}
+	return rule
+}
+
+// removeCommitted removes from the queues the activities that are in
+// the history.
+// The history and the queues are not written atomically, so an
+// activity may have been committed without being removed from its
+// queue.
+func (c *Client) removeCommitted() {
+	history := c.netflixClient.History
+	c.queue.RemoveFunc(func(e *QueueEntry) bool {
+		return history.Has(e.Activity.HistoryItem())
+	})
+	c.reviews.RemoveFunc(func(e *ReviewEntry) bool {
+		return history.Has(e.Activity.HistoryItem())
+	})
+}
+
+// needsReview returns the review entry of an activity that couldn't be
+// matched, if the user can help matching it.
+// This is the case when the activity matched several medias, or when
+// its title had to be guessed and didn't match anything.
+func (c *Client) needsReview(ctx context.Context, h *netflix.WatchActivity, matchErr error, now time.Time) (*ReviewEntry, bool) {
+	var ambiguous *candidatesError
+	if errors.As(matchErr, &ambiguous) {
+		return newReviewEntry(h, matchErr, ambiguous.Candidates, now), true
+	}
+
+	var lowConfidence *lowConfidenceError
+	if !h.Guessed || (!errors.Is(matchErr, errNoMatch) && !errors.As(matchErr, &lowConfidence)) {
+		return nil, false
+	}
+	candidates, err := c.findCandidates(ctx, h)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to find candidates to review", "media", h.String(), "error", err.Error())
+		return nil, false
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	return newReviewEntry(h, matchErr, candidates, now), true
+}
+
+// newReviewEntry returns a ReviewEntry with the provided candidates.
+func newReviewEntry(h *netflix.WatchActivity, reason error, candidates []*Match, now time.Time) *ReviewEntry {
+	if len(candidates) > maxReviewCandidates {
+		candidates = candidates[:maxReviewCandidates]
+	}
+	return &ReviewEntry{
+		Activity:   h,
+		Reason:     reason.Error(),
+		Candidates: candidates,
+		AddedAt:    now,
+	}
+}
+
+// findCandidates returns the medias an activity with a guessed title
+// may be, the most likely first.
+// The whole title is searched as a movie title, and for shows, the
+// episodes with a name similar to the guessed one are proposed.
+func (c *Client) findCandidates(ctx context.Context, h *netflix.WatchActivity) ([]*Match, error) {
+	candidates := []*Match{}
+
+	query := strings.ReplaceAll(h.RawTitle, `"`, "")
+	response, err := c.traktClient.Search(ctx, trakt.SearchRequest{
+		Type:   trakt.SearchTypeMovie,
+		Query:  query,
+		Show:   "",
+		Fields: nil,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("searching Trakt (query=%q): %w", query, err)
+	}
+	for _, m := range searchResults(response, trakt.SearchTypeMovie) {
+		candidates = append(candidates, &Match{
+			Movie:   m,
+			Show:    nil,
+			Episode: nil,
+			Score:   titleSimilarity(query, m.Title),
+		})
+	}
+
+	if h.IsShow {
+		response, err = c.traktClient.Search(ctx, trakt.SearchRequest{
+			Type:   trakt.SearchTypeShow,
+			Query:  h.SearchShow(),
+			Show:   "",
+			Fields: nil,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("searching Trakt show (show=%q): %w", h.SearchShow(), err)
+		}
+		shows := searchResults(response, trakt.SearchTypeShow)
+		for _, show := range shows[:min(len(shows), maxReviewShows)] {
+			seasons, err := c.traktClient.GetShowSeasons(ctx, showLookupID(*show), true)
+			if err != nil {
+				return nil, fmt.Errorf("getting Trakt show seasons (show=%q): %w", show.Title, err)
+			}
+			candidates = append(candidates, similarEpisodes(h, show, seasons, c.thresholds.lowConfidence)...)
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b *Match) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return candidates, nil
+}
+
+// similarEpisodes returns the episodes of the show with a name
+// similar to the one of the activity.
+func similarEpisodes(h *netflix.WatchActivity, show *trakt.Media, seasons []trakt.Season, minScore float64) []*Match {
+	matches := []*Match{}
+	for i := range seasons {
+		for j := range seasons[i].Episodes {
+			episode := &seasons[i].Episodes[j]
+			if score := titleSimilarity(h.EpisodeName, episode.Title); score >= minScore {
+				matches = append(matches, newEpisodeMatch(show, episode, score))
+			}
+		}
+	}
+	return matches
+}
+
+// reportReview reports an activity that got added to the review queue.
+func (c *Client) reportReview(ctx context.Context, entry *ReviewEntry) {
+	h := entry.Activity
+	slog.WarnContext(ctx, "activity needs to be reviewed", "media", h.String(), "reason", entry.Reason, "candidates", len(entry.Candidates))
+	c.slackClient.SendMessage(ctx, fmt.Sprintf("Trakt: Couldn't pick a match for: %s\nReason: %s\n%d candidates are waiting for review. Run `review` to pick one.", h.String(), entry.Reason, len(entry.Candidates)))
+}
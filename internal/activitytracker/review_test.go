@@ -0,0 +1,128 @@
+package activitytracker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/overrides"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewQueue(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "review_queue.json")
+	q, err := NewReviewQueue(path)
+	require.NoError(t, err)
+
+	activity := newEpisodeActivity("Some Show", 0, "Episode 1")
+	entry := &ReviewEntry{
+		Activity:   activity,
+		Reason:     errMultipleEpisodeMatches.Error(),
+		Candidates: []*Match{},
+		AddedAt:    time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+	q.Add(entry)
+	q.Add(entry)
+	require.Len(t, q.Entries, 1, "an activity should only be added once")
+	assert.True(t, q.Has(activity.HistoryItem()))
+	require.NoError(t, q.Write())
+
+	q, err = NewReviewQueue(path)
+	require.NoError(t, err)
+	require.Len(t, q.Entries, 1)
+	assert.Equal(t, entry, q.Entries[0])
+
+	q.RemoveFunc(func(*ReviewEntry) bool { return true })
+	assert.False(t, q.Has(activity.HistoryItem()))
+}
+
+func TestMultipleEpisodesCandidates(t *testing.T) {
+	t.Parallel()
+
+	episode := func(season, id int) trakt.Episode {
+		return trakt.Episode{Season: season, Number: 1, Title: "Episode 1", Year: 0, IDs: traktIDs(id)}
+	}
+	seasons := []trakt.Season{
+		{Number: 1, IDs: traktIDs(0), Episodes: []trakt.Episode{episode(1, 5001)}},
+		{Number: 2, IDs: traktIDs(0), Episodes: []trakt.Episode{episode(2, 6001)}},
+	}
+	show := &trakt.Media{Title: "Some Show", Year: 0, IDs: traktIDs(42), Votes: 0, Homepage: "", Network: ""}
+
+	_, _, err := findEpisodeInShowSeasons(newEpisodeActivity("Some Show", 0, "Episode 1"), seasons, testThresholds)
+	err = withShow(err, show)
+	require.ErrorIs(t, err, errMultipleEpisodeMatches)
+
+	var ambiguous *candidatesError
+	require.ErrorAs(t, err, &ambiguous)
+	require.Len(t, ambiguous.Candidates, 2)
+	for i, want := range []int{5001, 6001} {
+		assert.Equal(t, want, ambiguous.Candidates[i].IDs().Trakt)
+		assert.Equal(t, show, ambiguous.Candidates[i].Show)
+		assert.InDelta(t, 1, ambiguous.Candidates[i].Score, 0.001)
+	}
+}
+
+func TestNewOverrideRule(t *testing.T) {
+	t.Parallel()
+
+	season := 2
+	testCases := []struct {
+		name  string
+		match *Match
+		want  *overrides.Rule
+	}{
+		{
+			name:  "ignore",
+			match: nil,
+			want:  &overrides.Rule{Title: "Pain Hustlers", Pattern: "", Ignore: true, Movie: 0, Show: 0, Season: nil, Episode: 0},
+		},
+		{
+			name:  "movie",
+			match: &Match{Movie: &trakt.Media{Title: "Pain Hustlers", Year: 2023, IDs: traktIDs(764041), Votes: 0, Homepage: "", Network: ""}, Show: nil, Episode: nil, Score: 1},
+			want:  &overrides.Rule{Title: "Pain Hustlers", Pattern: "", Ignore: false, Movie: 764041, Show: 0, Season: nil, Episode: 0},
+		},
+		{
+			name: "episode",
+			match: &Match{
+				Movie:   nil,
+				Show:    &trakt.Media{Title: "Some Show", Year: 0, IDs: traktIDs(42), Votes: 0, Homepage: "", Network: ""},
+				Episode: &trakt.Episode{Season: 2, Number: 1, Title: "Episode 1", Year: 0, IDs: traktIDs(6001)},
+				Score:   1,
+			},
+			want: &overrides.Rule{Title: "Pain Hustlers", Pattern: "", Ignore: false, Movie: 0, Show: 42, Season: &season, Episode: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			activity := newEpisodeActivity("Some Show", 0, "Episode 1")
+			activity.RawTitle = "Pain Hustlers"
+			assert.Equal(t, tc.want, newOverrideRule(activity, tc.match))
+		})
+	}
+}
+
+func TestNeedsReview(t *testing.T) {
+	t.Parallel()
+
+	c := new(Client)
+	now := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	activity := newEpisodeActivity("Some Show", 0, "Episode 1")
+
+	candidates := []*Match{{Movie: nil, Show: nil, Episode: nil, Score: 1}}
+	entry, ok := c.needsReview(t.Context(), activity, &candidatesError{err: errMultipleEpisodeMatches, Candidates: candidates}, now)
+	require.True(t, ok, "ambiguous matches should be reviewed")
+	assert.Equal(t, &ReviewEntry{Activity: activity, Reason: errMultipleEpisodeMatches.Error(), Candidates: candidates, AddedAt: now}, entry)
+
+	_, ok = c.needsReview(t.Context(), activity, errNoMatch, now)
+	assert.False(t, ok, "misses of parsed titles should be retried")
+
+	_, ok = c.needsReview(t.Context(), activity, errors.New("http 500"), now)
+	assert.False(t, ok, "failures should be retried")
+}
@@ -0,0 +1,240 @@
+package activitytracker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// numberMismatchPenalty is applied to the score of titles that don't
+// contain the same numbers, since they are most likely different
+// medias (ex. "Episode 8" and "Episode 9", or "Emily in Paris" and
+// "Emily in Paris 2").
+const numberMismatchPenalty = 0.8
+
+// thresholds contains the scores used to decide whether two titles
+// match.
+type thresholds struct {
+	// accept is the minimum score for two titles to match.
+	accept float64
+	// lowConfidence is the minimum score for titles that don't match
+	// to be reported as a near-miss.
+	lowConfidence float64
+}
+
+// check returns nil if the score is high enough for the title to
+// match. Otherwise it returns a lowConfidenceError for the near-misses,
+// or errNoMatch.
+func (t thresholds) check(title string, score float64) error {
+	switch {
+	case score >= t.accept:
+		return nil
+	case score > 0 && score >= t.lowConfidence:
+		return &lowConfidenceError{Title: title, Score: score}
+	default:
+		return errNoMatch
+	}
+}
+
+// lowConfidenceError is returned when the closest Trakt title is
+// similar to the Netflix one, but not enough to be accepted.
+type lowConfidenceError struct {
+	// Title is the closest Trakt title.
+	Title string
+	// Score is the similarity between the titles.
+	Score float64
+}
+
+// Error implements the error interface.
+func (e *lowConfidenceError) Error() string {
+	return fmt.Sprintf("low confidence match: %q (score %.2f)", e.Title, e.Score)
+}
+
+// bestScored returns the items with the highest score, along with the
+// score.
+func bestScored[T any](items []T, score func(T) float64) ([]T, float64) {
+	best := []T{}
+	bestScore := 0.0
+	for _, item := range items {
+		s := score(item)
+		switch {
+		case s > bestScore:
+			best = []T{item}
+			bestScore = s
+		case s == bestScore && s > 0:
+			best = append(best, item)
+		}
+	}
+	return best, bestScore
+}
+
+// leadingArticles contains the articles that are ignored at the
+// beginning of a title (ex. "The Office" and "Office").
+var leadingArticles = map[string]struct{}{
+	"the": {}, "a": {}, "an": {},
+	"le": {}, "la": {}, "les": {}, "l": {},
+	"el": {}, "los": {}, "las": {},
+	"der": {}, "die": {}, "das": {},
+}
+
+// titleSimilarity returns how similar a Netflix title is to a Trakt
+// title, from 0 (completely different) to 1 (same title).
+// Titles that contain different numbers are penalized.
+//
+// Titles matched by stringMatches get a score of 1. Otherwise the
+// titles are normalized (accents, case, punctuation, leading
+// articles, "&" and "and", etc.), and compared using both the
+// Jaro-Winkler similarity, which is forgiving with typos, and the
+// Levenshtein distance of their sorted words, which is forgiving with
+// words in a different order.
+func titleSimilarity(netflixTitle, traktTitle string) float64 {
+	if stringMatches(netflixTitle, traktTitle) {
+		return 1
+	}
+
+	a := normalizeTitle(netflixTitle)
+	b := normalizeTitle(traktTitle)
+	// Netflix titles sometimes use "..." to indicate a longer title.
+	if strings.HasSuffix(netflixTitle, "...") && !strings.HasSuffix(traktTitle, "...") {
+		if r := []rune(b); len(r) > len([]rune(a)) {
+			b = string(r[:len([]rune(a))])
+		}
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	sortedA := strings.Fields(a)
+	slices.Sort(sortedA)
+	sortedB := strings.Fields(b)
+	slices.Sort(sortedB)
+	tokens := levenshteinSimilarity(strings.Join(sortedA, " "), strings.Join(sortedB, " "))
+	score := (jaroWinkler(a, b) + tokens) / 2
+	if !slices.Equal(numbers(sortedA), numbers(sortedB)) {
+		score *= numberMismatchPenalty
+	}
+	return score
+}
+
+// numbers returns the words that are numbers.
+func numbers(words []string) []string {
+	return slices.DeleteFunc(slices.Clone(words), func(w string) bool {
+		return strings.IndexFunc(w, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0
+	})
+}
+
+// normalizeTitle returns a version of the title that only contains
+// lowercase words, separated by a single space.
+func normalizeTitle(title string) string {
+	stringNormalizer := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	normalized, _, err := transform.String(stringNormalizer, title)
+	if err == nil {
+		title = normalized
+	}
+	title = strings.ToLower(title)
+	title = strings.NewReplacer("&", " and ", "+", " and ", "'", "", "’", "").Replace(title)
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, title)
+
+	words := strings.Fields(title)
+	if len(words) > 1 {
+		if _, ok := leadingArticles[words[0]]; ok {
+			words = words[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// levenshteinSimilarity returns the Levenshtein distance between a
+// and b, as a similarity between 0 and 1.
+func levenshteinSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity between a and b.
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(max(len(ra), len(rb))/2-1, 0)
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if matchedB[j] || ra[i] != rb[j] {
+				continue
+			}
+			matchedA[i] = true
+			matchedB[j] = true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
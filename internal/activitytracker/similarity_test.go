@@ -0,0 +1,92 @@
+package activitytracker
+
+import (
+	"testing"
+
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testThresholds contains the default thresholds of the config.
+var testThresholds = thresholds{accept: 0.9, lowConfidence: 0.75}
+
+func TestTitleSimilarity(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		netflixTitle string
+		traktTitle   string
+		wantMatch    bool
+		wantLow      bool
+	}{
+		{netflixTitle: "Love, Death & Robots", traktTitle: "Love, Death + Robots", wantMatch: true, wantLow: false},
+		{netflixTitle: "Love, Death & Robots", traktTitle: "Love Death and Robots", wantMatch: true, wantLow: false},
+		{netflixTitle: "Don't Look Up", traktTitle: "Dont Look Up", wantMatch: true, wantLow: false},
+		{netflixTitle: "The Office", traktTitle: "Office", wantMatch: true, wantLow: false},
+		{netflixTitle: "La Casa de Papel", traktTitle: "Casa de papel", wantMatch: true, wantLow: false},
+		{netflixTitle: "Rebecca", traktTitle: "Rebeca", wantMatch: true, wantLow: false},
+		{netflixTitle: "Arrested Development: Beef Consomme", traktTitle: "Arrested Development: Beef Consommé", wantMatch: true, wantLow: false},
+		{netflixTitle: "Spider-Man: Across the Spider-Verse", traktTitle: "Spider-Man: Across the Spiderverse", wantMatch: false, wantLow: true},
+		{netflixTitle: "Alice in Borderland", traktTitle: "Alice in Wonderland", wantMatch: false, wantLow: true},
+		{netflixTitle: "Episode 8", traktTitle: "Episode 9", wantMatch: false, wantLow: false},
+		{netflixTitle: "Emily in Paris", traktTitle: "Emily in Paris 2", wantMatch: false, wantLow: false},
+		{netflixTitle: "The Witcher: Blood Origin", traktTitle: "The Witcher", wantMatch: false, wantLow: false},
+		{netflixTitle: "Money Heist", traktTitle: "La Casa de Papel", wantMatch: false, wantLow: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.netflixTitle+" vs "+tc.traktTitle, func(t *testing.T) {
+			t.Parallel()
+
+			score := titleSimilarity(tc.netflixTitle, tc.traktTitle)
+			assert.GreaterOrEqual(t, score, 0.0)
+			assert.LessOrEqual(t, score, 1.0)
+			assert.Equal(t, tc.wantMatch, score >= testThresholds.accept, "score: %.3f", score)
+			assert.Equal(t, tc.wantLow, !tc.wantMatch && score >= testThresholds.lowConfidence, "score: %.3f", score)
+		})
+	}
+
+	assert.InDelta(t, 1.0, titleSimilarity("Arrested Development: Justice is Blind", "Arrested Development: Justice Is Blind"), 0, "exact matches should have a score of 1")
+}
+
+func TestThresholdsCheck(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, testThresholds.check("Rebeca", 0.95))
+	require.ErrorIs(t, testThresholds.check("Alice in Wonderland", 0.5), errNoMatch)
+	require.ErrorIs(t, testThresholds.check("", 0), errNoMatch)
+
+	err := testThresholds.check("Alice in Wonderland", 0.76)
+	var lowConfidence *lowConfidenceError
+	require.ErrorAs(t, err, &lowConfidence)
+	assert.Equal(t, "Alice in Wonderland", lowConfidence.Title)
+	assert.Equal(t, `low confidence match: "Alice in Wonderland" (score 0.76)`, err.Error())
+}
+
+func TestFindEpisodeWithSimilarName(t *testing.T) {
+	t.Parallel()
+
+	episode := func(number int, title string) trakt.Episode {
+		return trakt.Episode{Season: 1, Number: number, Title: title, Year: 2019, IDs: traktIDs(100 + number)}
+	}
+	seasons := []trakt.Season{{
+		Number:   1,
+		IDs:      traktIDs(1),
+		Episodes: []trakt.Episode{episode(1, "Sonnie's Edge"), episode(2, "Three Robots"), episode(3, "The Witness")},
+	}}
+
+	got, score, err := findEpisodeInShowSeasons(newEpisodeActivity("Love, Death & Robots", 1, "Sonnies Edge"), seasons, testThresholds)
+	require.NoError(t, err)
+	assert.Equal(t, 101, got.IDs.Trakt)
+	assert.InDelta(t, 1.0, score, 0)
+
+	got, score, err = findEpisodeInShowSeasons(newEpisodeActivity("Love, Death & Robots", 1, "Three Robots: Exit"), seasons, testThresholds)
+	var lowConfidence *lowConfidenceError
+	require.ErrorAs(t, err, &lowConfidence, "score: %.3f", score)
+	assert.Nil(t, got)
+	assert.Equal(t, "Three Robots", lowConfidence.Title)
+
+	got, _, err = findEpisodeInShowSeasons(newEpisodeActivity("Love, Death & Robots", 1, "The Witnes"), seasons, testThresholds)
+	require.NoError(t, err)
+	assert.Equal(t, 103, got.IDs.Trakt)
+}
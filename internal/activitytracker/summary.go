@@ -0,0 +1,101 @@
+package activitytracker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ItemStatus represents the outcome of the sync of an activity.
+type ItemStatus string
+
+const (
+	// ItemStatusAdded means the activity got added to Trakt.
+	ItemStatusAdded ItemStatus = "added"
+	// ItemStatusNotFound means the activity got matched, but Trakt
+	// didn't find the media we sent.
+	ItemStatusNotFound ItemStatus = "not_found"
+	// ItemStatusUnmatched means the activity couldn't be matched with
+	// a media on Trakt.
+	ItemStatusUnmatched ItemStatus = "unmatched"
+	// ItemStatusLowConfidence means the activity couldn't be matched,
+	// but a Trakt media has a similar title.
+	ItemStatusLowConfidence ItemStatus = "low_confidence"
+	// ItemStatusNeedsReview means the activity matched several medias,
+	// or its title couldn't be parsed, and it's waiting for the user
+	// to pick its match.
+	ItemStatusNeedsReview ItemStatus = "needs_review"
+	// ItemStatusFailed means the activity got matched, but the request
+	// to Trakt failed.
+	ItemStatusFailed ItemStatus = "failed"
+)
+
+// SummaryItem contains the outcome of the sync of an activity.
+type SummaryItem struct {
+	Activity string     `json:"activity"`
+	Status   ItemStatus `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	// Score is the similarity between the Netflix and Trakt titles.
+	// 0 if unknown.
+	Score float64 `json:"score,omitempty"`
+}
+
+// Summary contains the outcome of a run.
+type Summary struct {
+	Items []SummaryItem `json:"items"`
+}
+
+// add records the outcome of the sync of an activity.
+// score is the similarity between the Netflix and Trakt titles, or 0
+// if unknown.
+func (s *Summary) add(activity fmt.Stringer, status ItemStatus, score float64, err string) {
+	s.Items = append(s.Items, SummaryItem{
+		Activity: activity.String(),
+		Status:   status,
+		Error:    err,
+		Score:    score,
+	})
+}
+
+// Count returns the number of items with the given status.
+func (s *Summary) Count(status ItemStatus) int {
+	count := 0
+	for _, item := range s.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Complete returns whether every item got added to Trakt.
+func (s *Summary) Complete() bool {
+	return s.Count(ItemStatusAdded) == len(s.Items)
+}
+
+// Failed returns whether nothing got added because Trakt failed to
+// add the matched activities.
+func (s *Summary) Failed() bool {
+	return s.Count(ItemStatusAdded) == 0 && s.Count(ItemStatusFailed) > 0
+}
+
+// String implements the Stringer interface.
+// It returns the counts, followed by the outcome of each item.
+// The score of the items that weren't exact matches is displayed.
+func (s *Summary) String() string {
+	out := strings.Builder{}
+	fmt.Fprintf(&out, "%d added, %d not found by Trakt, %d unmatched, %d low confidence, %d to review, %d failed",
+		s.Count(ItemStatusAdded),
+		s.Count(ItemStatusNotFound),
+		s.Count(ItemStatusUnmatched),
+		s.Count(ItemStatusLowConfidence),
+		s.Count(ItemStatusNeedsReview),
+		s.Count(ItemStatusFailed),
+	)
+	for _, item := range s.Items {
+		fmt.Fprintf(&out, "\n- [%s] %s", item.Status, item.Activity)
+		if item.Score > 0 && item.Score < 1 {
+			fmt.Fprintf(&out, " (score %.2f)", item.Score)
+		}
+	}
+	return out.String()
+}
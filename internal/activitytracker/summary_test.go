@@ -0,0 +1,59 @@
+package activitytracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	t.Parallel()
+
+	movie := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81614419",
+		RawTitle:    "Pain Hustlers",
+		Title:       "Pain Hustlers",
+		EpisodeName: "",
+		IsShow:      false,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+	episode := &netflix.WatchActivity{
+		Date:        time.Time{},
+		VideoID:     "81307056",
+		RawTitle:    `Goedam: Collection: "Threshold"`,
+		Title:       "Goedam",
+		EpisodeName: "Threshold",
+		IsShow:      true,
+		Season:      0,
+		IsRewatch:   false,
+		Guessed:     false,
+		Metadata:    nil,
+		Year:        0,
+	}
+
+	s := &Summary{Items: []SummaryItem{}}
+	assert.True(t, s.Complete(), "an empty run should be complete")
+	assert.False(t, s.Failed(), "an empty run should not be a failure")
+	s.add(movie, ItemStatusFailed, 1, "failed to add")
+	assert.True(t, s.Failed(), "a run that added nothing should be a failure")
+	s.Items = []SummaryItem{}
+	s.add(movie, ItemStatusAdded, 1, "")
+	assert.True(t, s.Complete())
+	assert.False(t, s.Failed())
+	s.add(episode, ItemStatusNotFound, 0.93, "not found by Trakt (trakt ID 1001)")
+	s.add(episode, ItemStatusLowConfidence, 0.8, `low confidence match: "Threshold" (score 0.80)`)
+
+	assert.Equal(t, 1, s.Count(ItemStatusAdded))
+	assert.Equal(t, 1, s.Count(ItemStatusNotFound))
+	assert.Equal(t, 0, s.Count(ItemStatusUnmatched))
+	assert.Equal(t, 1, s.Count(ItemStatusLowConfidence))
+	assert.False(t, s.Complete())
+	assert.Equal(t, "1 added, 1 not found by Trakt, 0 unmatched, 1 low confidence, 0 to review, 0 failed\n- [added] Pain Hustlers\n- [not_found] Goedam: Threshold (score 0.93)\n- [low_confidence] Goedam: Threshold (score 0.80)", s.String())
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// sharedConfig contains the configuration shared by all the binaries.
+type sharedConfig struct {
+	// ProfilesFile is the path of a file listing the profiles to sync.
+	ProfilesFile string `env:"PROFILES_FILE"`
+}
+
+// Load loads the configuration file at path (which can be empty), fills
+// target with the values that are not part of a profile, and returns
+// the profiles to sync.
+// target can be nil if the binary doesn't need any other values.
+func Load(ctx context.Context, path string, target any) ([]*Profile, error) {
+	file, err := LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load config file: %w", err)
+	}
+
+	if target != nil {
+		if err = file.Process(ctx, target); err != nil {
+			return nil, fmt.Errorf("parse the config: %w", err)
+		}
+	}
+	var shared sharedConfig
+	if err = file.Process(ctx, &shared); err != nil {
+		return nil, fmt.Errorf("parse the config: %w", err)
+	}
+
+	profiles, err := LoadProfiles(ctx, file, shared.ProfilesFile)
+	if err != nil {
+		return nil, fmt.Errorf("load profiles: %w", err)
+	}
+	if err = file.CheckUnknownKeys(); err != nil {
+		return nil, fmt.Errorf("parse the config: %w", err)
+	}
+	return profiles, nil
+}
+
+// FindProfile returns the profile with the given name. The name can be
+// empty if there's only one profile.
+func FindProfile(profiles []*Profile, name string) (*Profile, error) {
+	if name == "" {
+		if len(profiles) > 1 {
+			return nil, errors.New("a profile must be selected when there are multiple profiles")
+		}
+		return profiles[0], nil
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("profile %q not found", name)
+}
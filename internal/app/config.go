@@ -0,0 +1,263 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// File represents a YAML configuration file.
+//
+// The keys of the file are the names of the environment variables,
+// lowercased and nested by prefix. For example, NETFLIX_COOKIE is set
+// with:
+//
+//	netflix:
+//	  cookie: value
+//
+// The file can also contain a list of profiles, under "profiles".
+type File struct {
+	// top contains the values that are not part of a profile.
+	top *source
+	// profiles contains the values of each profile.
+	profiles []*source
+}
+
+// source represents a set of values a configuration can be read from.
+type source struct {
+	// file is the path of the file containing the values. Empty if the
+	// values come from the environment.
+	file string
+	// values contains the values, keyed by the name of their
+	// environment variable.
+	values map[string]string
+	// keys contains the path of the values in the file (ex.
+	// "trakt.client_id"), keyed by the name of their environment
+	// variable.
+	keys map[string]string
+	// used contains the names of the environment variables that have
+	// been looked up, even if the value came from another source.
+	used map[string]struct{}
+}
+
+// envSource is the source that reads from the environment.
+var envSource = &source{
+	file:   "",
+	values: nil,
+	keys:   nil,
+	used:   nil,
+}
+
+// LoadFile loads the configuration file at the given path.
+// An empty path returns an empty configuration, meaning everything
+// will be read from the environment.
+func LoadFile(path string) (*File, error) {
+	f := &File{
+		top:      newSource(path),
+		profiles: []*source{},
+	}
+	if path == "" {
+		return f, nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var content map[string]any
+	if err = yaml.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if raw, ok := content["profiles"]; ok {
+		delete(content, "profiles")
+		profiles, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for %q in %s: expected a list", "profiles", path)
+		}
+		for i, raw := range profiles {
+			values, ok := raw.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid value for %q in %s: expected a map", "profiles["+strconv.Itoa(i)+"]", path)
+			}
+			s := newSource(path)
+			s.add("profiles["+strconv.Itoa(i)+"].", "", values)
+			f.profiles = append(f.profiles, s)
+		}
+	}
+	f.top.add("", "", content)
+	return f, nil
+}
+
+// Process fills target with the configuration. Environment variables
+// take precedence over the values of the file.
+// Errors name the key (or the environment variable) that caused them.
+func (f *File) Process(ctx context.Context, target any) error {
+	return process(ctx, target, envSource, f.top)
+}
+
+// HasProfiles returns whether the file contains profiles.
+func (f *File) HasProfiles() bool {
+	return len(f.profiles) > 0
+}
+
+// CheckUnknownKeys returns an error if the file contains keys that
+// have never been used. Should be called once the whole configuration
+// has been processed.
+func (f *File) CheckUnknownKeys() error {
+	for _, s := range append([]*source{f.top}, f.profiles...) {
+		unknown := []string{}
+		for env, key := range s.keys {
+			if _, ok := s.used[env]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			slices.Sort(unknown)
+			return fmt.Errorf("unknown key %q in %s", unknown[0], s.file)
+		}
+	}
+	return nil
+}
+
+func newSource(path string) *source {
+	return &source{
+		file:   path,
+		values: map[string]string{},
+		keys:   map[string]string{},
+		used:   map[string]struct{}{},
+	}
+}
+
+// add adds the provided values to the source. Nested maps are
+// flattened, and lists are joined with a comma.
+func (s *source) add(keyPrefix, envPrefix string, values map[string]any) {
+	for k, v := range values {
+		key := keyPrefix + k
+		env := envPrefix + strings.ToUpper(k)
+		switch v := v.(type) {
+		case map[string]any:
+			s.add(key+".", env+"_", v)
+			continue
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			s.values[env] = strings.Join(items, ",")
+		case nil:
+			s.values[env] = ""
+		default:
+			s.values[env] = fmt.Sprint(v)
+		}
+		s.keys[env] = key
+	}
+}
+
+// lookup returns the value of the given environment variable.
+func (s *source) lookup(env string) (string, bool) {
+	if s == envSource {
+		return os.LookupEnv(env)
+	}
+	s.used[env] = struct{}{}
+	v, ok := s.values[env]
+	return v, ok
+}
+
+// name returns a human readable name of the given environment variable,
+// for when it comes from this source.
+func (s *source) name(env string) string {
+	if s == envSource {
+		return env
+	}
+	return fmt.Sprintf("%q in %s", s.keys[env], s.file)
+}
+
+// process fills target with the values of the provided sources. When a
+// value is set in multiple sources, the first one wins.
+func process(ctx context.Context, target any, sources ...*source) error {
+	// envconfig processes the fields one by one and stops at the first
+	// error, so the last lookup is the one that failed.
+	var lastEnv string
+	var lastSource *source
+	lookuper := envconfig.LookuperFunc(func(env string) (string, bool) {
+		lastEnv = env
+		lastSource = nil
+		var value string
+		for _, s := range sources {
+			// We look up every source to know which keys are used
+			if v, ok := s.lookup(env); ok && lastSource == nil {
+				lastSource = s
+				value = v
+			}
+		}
+		return value, lastSource != nil
+	})
+
+	err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   target,
+		Lookuper: lookuper,
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, envconfig.ErrMissingRequired):
+		return fmt.Errorf("missing required value for %s", requiredName(target, lastEnv, sources))
+	case lastSource != nil:
+		return fmt.Errorf("invalid value for %s: %w", lastSource.name(lastEnv), err)
+	default:
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+}
+
+// requiredName returns a human readable name for a required value that
+// has not been set.
+func requiredName(target any, env string, sources []*source) string {
+	for _, s := range sources {
+		if s == envSource || s.file == "" {
+			continue
+		}
+		if key, ok := fileKeys(reflect.TypeOf(target), "", "")[env]; ok {
+			return fmt.Sprintf("%s (or %q in %s)", env, key, s.file)
+		}
+	}
+	return env
+}
+
+// fileKeys returns the keys of the configuration file that can be
+// used to fill the provided type, keyed by the name of their
+// environment variable.
+func fileKeys(t reflect.Type, keyPrefix, envPrefix string) map[string]string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	keys := map[string]string{}
+	if t.Kind() != reflect.Struct {
+		return keys
+	}
+	for i := range t.NumField() {
+		tag, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if prefix, ok := strings.CutPrefix(opts, "prefix="); ok {
+			key := keyPrefix + strings.ToLower(strings.TrimSuffix(prefix, "_")) + "."
+			maps.Copy(keys, fileKeys(t.Field(i).Type, key, envPrefix+prefix))
+			continue
+		}
+		if name != "" {
+			keys[envPrefix+name] = keyPrefix + strings.ToLower(name)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestFileProcess(t *testing.T) {
+	// Can't be run in parallel since we change the env
+	t.Setenv("TRAKT_CLIENT_ID", "env-client-id")
+	t.Setenv("NETFLIX_COOKIE", "env-cookie")
+
+	path := writeConfig(t, `
+cron_specs: "@daily"
+trakt:
+  client_id: file-client-id
+  redirect_uri: http://localhost
+netflix:
+  timezone: Europe/Paris
+slack:
+  webhooks:
+    - https://hooks.slack.com/a
+    - https://hooks.slack.com/b
+sync:
+  retry_delay: 2h
+`)
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+
+	var top struct {
+		CronSpecs string `env:"CRON_SPECS,default=@hourly"`
+	}
+	require.NoError(t, cfg.Process(t.Context(), &top))
+	assert.Equal(t, "@daily", top.CronSpecs)
+
+	profiles, err := LoadProfiles(t.Context(), cfg, "")
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	p := profiles[0]
+	assert.Empty(t, p.Name)
+	assert.Equal(t, "env-client-id", p.Trakt.ClientID, "the env should override the file")
+	assert.Equal(t, "http://localhost", p.Trakt.RedirectURI)
+	assert.Equal(t, "env-cookie", p.Netflix.Cookie)
+	assert.Equal(t, "Europe/Paris", p.Netflix.Timezone)
+	assert.Equal(t, []string{"https://hooks.slack.com/a", "https://hooks.slack.com/b"}, p.Slack.WebhookURLs)
+	assert.Equal(t, 2*time.Hour, p.Sync.RetryDelay)
+	assert.Equal(t, 5, p.Sync.MaxAttempts)
+
+	require.NoError(t, cfg.CheckUnknownKeys())
+}
+
+func TestFileProfiles(t *testing.T) {
+	// Can't be run in parallel since we change the env
+	t.Setenv("TRAKT_CLIENT_ID", "")
+	t.Setenv("NETFLIX_COOKIE", "")
+
+	path := writeConfig(t, `
+trakt:
+  client_id: shared-client-id
+  redirect_uri: http://localhost
+profiles:
+  - name: melvin
+    netflix:
+      cookie: cookie-melvin
+  - name: kids
+    netflix:
+      cookie: cookie-kids
+    trakt:
+      client_id: kids-client-id
+`)
+	// t.Setenv with an empty value still sets the variable, which
+	// would take precedence over the file
+	require.NoError(t, os.Unsetenv("TRAKT_CLIENT_ID"))
+	require.NoError(t, os.Unsetenv("NETFLIX_COOKIE"))
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+
+	profiles, err := LoadProfiles(t.Context(), cfg, "")
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "melvin", profiles[0].Name)
+	assert.Equal(t, "shared-client-id", profiles[0].Trakt.ClientID)
+	assert.Equal(t, "cookie-melvin", profiles[0].Netflix.Cookie)
+	assert.Equal(t, "kids", profiles[1].Name)
+	assert.Equal(t, "kids-client-id", profiles[1].Trakt.ClientID)
+	assert.Equal(t, "cookie-kids", profiles[1].Netflix.Cookie)
+
+	require.NoError(t, cfg.CheckUnknownKeys())
+}
+
+func TestFileErrors(t *testing.T) {
+	// Can't be run in parallel since we change the env
+	t.Setenv("TRAKT_CLIENT_ID", "client-id")
+	t.Setenv("TRAKT_REDIRECT_URI", "http://localhost")
+	t.Setenv("NETFLIX_COOKIE", "cookie")
+
+	testCases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "invalid value",
+			content: "sync:\n  max_attempts: five\n",
+			wantErr: `invalid value for "sync.max_attempts" in `,
+		},
+		{
+			name:    "invalid value in a profile",
+			content: "profiles:\n  - name: melvin\n    netflix:\n      rewatches: maybe\n",
+			wantErr: `parse profile 0: invalid value for "profiles[0].netflix.rewatches" in `,
+		},
+		{
+			name:    "unknown key",
+			content: "trakt:\n  clientid: client-id\n",
+			wantErr: `unknown key "trakt.clientid" in `,
+		},
+		{
+			name:    "unknown key in a profile",
+			content: "profiles:\n  - name: melvin\n    netflx:\n      cookie: cookie\n",
+			wantErr: `unknown key "profiles[0].netflx.cookie" in `,
+		},
+		{
+			name:    "invalid profiles",
+			content: "profiles: melvin\n",
+			wantErr: `invalid value for "profiles" in `,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := LoadFile(writeConfig(t, tc.content))
+			if err == nil {
+				_, err = LoadProfiles(t.Context(), cfg, "")
+			}
+			if err == nil {
+				err = cfg.CheckUnknownKeys()
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestFileMissingRequiredValue(t *testing.T) {
+	// Can't be run in parallel since we change the env
+	t.Setenv("TRAKT_CLIENT_ID", "client-id")
+	t.Setenv("TRAKT_REDIRECT_URI", "http://localhost")
+	t.Setenv("NETFLIX_COOKIE", "")
+	require.NoError(t, os.Unsetenv("NETFLIX_COOKIE"))
+
+	path := writeConfig(t, "netflix:\n  timezone: UTC\n")
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+
+	_, err = LoadProfiles(t.Context(), cfg, "")
+	require.EqualError(t, err, `missing required value for NETFLIX_COOKIE (or "netflix.cookie" in `+path+`)`)
+}
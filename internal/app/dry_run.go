@@ -0,0 +1,29 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+)
+
+// DryRun prints to w what would be synced for each profile, without
+// syncing anything.
+// The profiles must have been created in dry-run mode.
+func DryRun(ctx context.Context, w io.Writer, trackers []*Tracker) error {
+	for _, t := range trackers {
+		if t.Profile.Name != "" {
+			fmt.Fprintf(w, "Profile %s:\n", t.Profile.Name)
+		}
+		items, err := t.Activity.DryRun(ctx)
+		if err != nil {
+			return fmt.Errorf("dry run of profile %q: %w", t.Profile.Name, err)
+		}
+		if err = activitytracker.PrintDryRun(w, items); err != nil {
+			return fmt.Errorf("print dry run of profile %q: %w", t.Profile.Name, err)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
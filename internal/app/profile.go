@@ -0,0 +1,174 @@
+// Package app contains the configuration and the bootstrapping logic
+// shared by the binaries.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/pathutil"
+	"github.com/Nivl/trakt-netflix/internal/slack"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// Profile contains the configuration needed to sync a Netflix profile
+// with a Trakt account.
+type Profile struct {
+	// Name is the name of the profile. It is empty when the
+	// configuration doesn't contain any profiles.
+	Name    string
+	Trakt   trakt.ClientConfig     `env:",prefix=TRAKT_"`
+	Slack   slack.Config           `env:",prefix=SLACK_"`
+	Netflix netflix.Config         `env:",prefix=NETFLIX_"`
+	Sync    activitytracker.Config `env:",prefix=SYNC_"`
+}
+
+// LoadProfiles returns the profiles to sync.
+//
+// The profiles are read from the YAML file at path, or from the
+// configuration file if path is empty. If no profiles are defined, a
+// single profile is created from the configuration file and the
+// environment.
+//
+// The keys of a profile are the same as the ones of the configuration
+// file. Values not set in a profile are read from the environment,
+// then from the configuration file, which allows sharing values across
+// profiles (ex. TRAKT_CLIENT_ID).
+//
+// The files of a named profile are stored in a directory named after
+// the profile, unless specified otherwise.
+func LoadProfiles(ctx context.Context, cfg *File, path string) ([]*Profile, error) {
+	if path != "" {
+		file, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(file.profiles) == 0 {
+			return nil, fmt.Errorf("%s doesn't contain any profiles", path)
+		}
+		if len(file.top.keys) > 0 {
+			// We don't process the top of the file, so everything
+			// it contains is unknown
+			return nil, file.CheckUnknownKeys()
+		}
+		if len(cfg.profiles) > 0 {
+			return nil, fmt.Errorf("profiles cannot be set in both %s and %s", path, cfg.top.file)
+		}
+		cfg.profiles = file.profiles
+	}
+
+	if len(cfg.profiles) == 0 {
+		p := &Profile{} //nolint:exhaustruct // Will be filled by envconfig
+		if err := cfg.Process(ctx, p); err != nil {
+			return nil, err
+		}
+		return []*Profile{p}, nil
+	}
+
+	profiles := make([]*Profile, 0, len(cfg.profiles))
+	for i, values := range cfg.profiles {
+		p := &Profile{} //nolint:exhaustruct // Will be filled by envconfig
+		p.Name, _ = values.lookup("NAME")
+		if err := process(ctx, p, values, envSource, cfg.top); err != nil {
+			return nil, fmt.Errorf("parse profile %d: %w", i, err)
+		}
+		if err := p.setDefaultPaths(); err != nil {
+			return nil, fmt.Errorf("parse profile %d: %w", i, err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	if err := validateProfiles(profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// setDefaultPaths makes the files of the profile default to the
+// directory of the profile.
+func (p *Profile) setDefaultPaths() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	if p.Name != filepath.Base(p.Name) || strings.HasPrefix(p.Name, ".") {
+		return fmt.Errorf("invalid name %q", p.Name)
+	}
+
+	for path, name := range map[*string]string{
+		&p.Trakt.RelAuthFilePath:      "trakt_auth.json",
+		&p.Netflix.RelHistoryFilePath: "history",
+		&p.Sync.RelQueueFilePath:      "retry_queue.json",
+		&p.Sync.RelBackfillFilePath:   "backfill.json",
+		&p.Sync.RelImportFilePath:     "import.json",
+		&p.Sync.RelReviewFilePath:     "review_queue.json",
+		&p.Sync.RelCacheFilePath:      "match_cache.json",
+	} {
+		if *path == "" {
+			*path = filepath.Join(p.Name, name)
+		}
+	}
+	return nil
+}
+
+// files returns the paths of the files of the profile, relative to
+// the config directory.
+func (p *Profile) files() []string {
+	return []string{
+		p.Trakt.RelAuthFilePath,
+		p.Netflix.RelHistoryFilePath,
+		p.Sync.RelQueueFilePath,
+		p.Sync.RelBackfillFilePath,
+		p.Sync.RelImportFilePath,
+		p.Sync.RelReviewFilePath,
+		p.Sync.RelCacheFilePath,
+	}
+}
+
+// lockPath returns the path of the file locked while the files of the
+// profile are being used.
+func (p *Profile) lockPath() string {
+	return filepath.Join(pathutil.ConfigDir(), p.Netflix.RelHistoryFilePath+".lock")
+}
+
+// createDirs creates the directories containing the files of the
+// profile.
+func (p *Profile) createDirs() error {
+	for _, path := range p.files() {
+		if path == "" {
+			continue
+		}
+		dir := filepath.Dir(filepath.Join(pathutil.ConfigDir(), path))
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// validateProfiles makes sure the profiles don't share any names or
+// files.
+func validateProfiles(profiles []*Profile) error {
+	names := map[string]struct{}{}
+	files := map[string]string{}
+	for _, p := range profiles {
+		if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("profile %q is defined multiple times", p.Name)
+		}
+		names[p.Name] = struct{}{}
+
+		for _, path := range p.files() {
+			path = filepath.Clean(path)
+			if other, ok := files[path]; ok {
+				return fmt.Errorf("profiles %q and %q use the same file: %s", other, p.Name, path)
+			}
+			files[path] = p.Name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	// Can't be run in parallel since we change the env
+	t.Setenv("TRAKT_CLIENT_ID", "shared-client-id")
+	t.Setenv("TRAKT_REDIRECT_URI", "http://localhost")
+	t.Setenv("NETFLIX_COOKIE", "")
+
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	err := os.WriteFile(path, []byte(`
+profiles:
+  - name: melvin
+    netflix:
+      cookie: cookie-melvin
+      account_id: account-melvin
+    slack:
+      webhooks:
+        - https://hooks.slack.com/melvin
+        - https://hooks.slack.com/shared
+  - name: kids
+    netflix:
+      cookie: cookie-kids
+      rewatches: true
+    trakt:
+      auth_file_rel_path: trakt_kids.json
+`), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := LoadFile("")
+	require.NoError(t, err)
+	profiles, err := LoadProfiles(t.Context(), cfg, path)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	melvin := profiles[0]
+	assert.Equal(t, "melvin", melvin.Name)
+	assert.Equal(t, "shared-client-id", melvin.Trakt.ClientID)
+	assert.Equal(t, "cookie-melvin", melvin.Netflix.Cookie)
+	assert.Equal(t, "account-melvin", melvin.Netflix.AccountID)
+	assert.False(t, melvin.Netflix.Rewatches, "rewatches should not be tracked by default")
+	assert.Equal(t, []string{"https://hooks.slack.com/melvin", "https://hooks.slack.com/shared"}, melvin.Slack.WebhookURLs)
+	assert.Equal(t, filepath.Join("melvin", "trakt_auth.json"), melvin.Trakt.RelAuthFilePath)
+	assert.Equal(t, filepath.Join("melvin", "history"), melvin.Netflix.RelHistoryFilePath)
+	assert.Equal(t, filepath.Join("melvin", "retry_queue.json"), melvin.Sync.RelQueueFilePath)
+
+	kids := profiles[1]
+	assert.Equal(t, "kids", kids.Name)
+	assert.Equal(t, "cookie-kids", kids.Netflix.Cookie)
+	assert.True(t, kids.Netflix.Rewatches)
+	assert.Empty(t, kids.Slack.WebhookURLs)
+	assert.Equal(t, "trakt_kids.json", kids.Trakt.RelAuthFilePath)
+	assert.Equal(t, filepath.Join("kids", "history"), kids.Netflix.RelHistoryFilePath)
+}
+
+func TestLoadProfilesValidation(t *testing.T) {
+	t.Setenv("TRAKT_CLIENT_ID", "shared-client-id")
+	t.Setenv("TRAKT_REDIRECT_URI", "http://localhost")
+	t.Setenv("NETFLIX_COOKIE", "shared-cookie")
+
+	testCases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "no profiles",
+			content: "profiles: []",
+			wantErr: "doesn't contain any profiles",
+		},
+		{
+			name:    "missing name",
+			content: "profiles: [{netflix: {cookie: a}}]",
+			wantErr: "parse profile 0: name is required",
+		},
+		{
+			name:    "invalid name",
+			content: "profiles: [{name: ../melvin}]",
+			wantErr: `parse profile 0: invalid name "../melvin"`,
+		},
+		{
+			name:    "duplicate name",
+			content: "profiles: [{name: melvin}, {name: melvin}]",
+			wantErr: `profile "melvin" is defined multiple times`,
+		},
+		{
+			name:    "shared file",
+			content: "profiles: [{name: melvin, trakt: {auth_file_rel_path: auth.json}}, {name: kids, trakt: {auth_file_rel_path: auth.json}}]",
+			wantErr: `profiles "melvin" and "kids" use the same file: auth.json`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profiles.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			cfg, err := LoadFile("")
+			require.NoError(t, err)
+			_, err = LoadProfiles(t.Context(), cfg, path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
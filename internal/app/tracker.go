@@ -0,0 +1,236 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/errutil"
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+	"github.com/Nivl/trakt-netflix/internal/slack"
+	"github.com/Nivl/trakt-netflix/internal/trakt"
+)
+
+// maxRuns is the number of runs kept in memory for each profile.
+const maxRuns = 20
+
+var (
+	// ErrSyncInProgress is returned when a profile is synced while
+	// it's already being synced, by this process or by another one.
+	ErrSyncInProgress = errors.New("a sync is already in progress")
+	// ErrClosed is returned when a profile is used after its tracker
+	// got closed.
+	ErrClosed = errors.New("the profile is shutting down")
+)
+
+// Run contains the outcome of a sync.
+type Run struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	// Summary is nil if the run failed before syncing anything.
+	Summary *activitytracker.Summary
+	// Err is empty if the run succeeded.
+	Err string
+}
+
+// Tracker contains all the clients needed to sync a profile.
+//
+// The clients are not safe for concurrent use, so the goroutines
+// sharing a tracker must use them through Sync, Do, or TryDo.
+// Sync and Do also lock the files of the profile, so they can be
+// shared with other processes (ex. two containers using the same
+// config directory).
+type Tracker struct {
+	Profile  *Profile
+	Trakt    *trakt.Client
+	Netflix  *netflix.Client
+	Slack    *slack.Client
+	Activity *activitytracker.Client
+
+	// mu prevents the clients from being used by several goroutines
+	// at once.
+	mu sync.Mutex
+	// closed is true once the tracker got closed. Protected by mu.
+	closed bool
+	// syncing is true while the profile is being synced.
+	syncing atomic.Bool
+	// runsMu protects runs, which can be read during a run.
+	runsMu sync.Mutex
+	// runs contains the last runs, the most recent first.
+	runs []Run
+}
+
+// NewTracker creates the clients of the given profile.
+func NewTracker(p *Profile) (*Tracker, error) {
+	if err := p.createDirs(); err != nil {
+		return nil, err
+	}
+
+	traktClient, err := trakt.NewClient(p.Trakt)
+	if err != nil {
+		return nil, fmt.Errorf("create trakt client: %w", err)
+	}
+
+	netflixClient, err := netflix.NewClient(p.Netflix)
+	if err != nil {
+		return nil, fmt.Errorf("create netflix client: %w", err)
+	}
+
+	slackClient := slack.NewClient(p.Slack)
+
+	activity, err := activitytracker.New(p.Sync, traktClient, netflixClient, slackClient)
+	if err != nil {
+		return nil, fmt.Errorf("create activity tracker: %w", err)
+	}
+
+	return &Tracker{
+		Profile:  p,
+		Trakt:    traktClient,
+		Netflix:  netflixClient,
+		Slack:    slackClient,
+		Activity: activity,
+		mu:       sync.Mutex{},
+		closed:   false,
+		syncing:  atomic.Bool{},
+		runsMu:   sync.Mutex{},
+		runs:     []Run{},
+	}, nil
+}
+
+// Sync fetches the viewing activity of the profile and syncs it with
+// Trakt, once the other operations on the profile are done.
+// The outcome of the run is recorded.
+// ErrSyncInProgress is returned if the profile is already being synced.
+func (t *Tracker) Sync(ctx context.Context) (summary *activitytracker.Summary, err error) {
+	if !t.syncing.CompareAndSwap(false, true) {
+		return nil, ErrSyncInProgress
+	}
+	defer t.syncing.Store(false)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrClosed
+	}
+
+	lock, err := fileutil.TryLock(t.Profile.lockPath())
+	if err != nil {
+		if errors.Is(err, fileutil.ErrLocked) {
+			return nil, fmt.Errorf("%w by another process", ErrSyncInProgress)
+		}
+		return nil, fmt.Errorf("lock profile: %w", err)
+	}
+	defer errutil.RunAndSetError(lock.Unlock, &err, "unlock profile")
+	if err = t.reload(ctx); err != nil {
+		return nil, err
+	}
+
+	run := Run{
+		StartedAt: time.Now(),
+		Duration:  0,
+		Summary:   nil,
+		Err:       "",
+	}
+	summary, err = t.Activity.Run(ctx)
+	run.Duration = time.Since(run.StartedAt)
+	run.Summary = summary
+	if err != nil {
+		run.Err = err.Error()
+	}
+
+	t.runsMu.Lock()
+	defer t.runsMu.Unlock()
+	t.runs = slices.Insert(t.runs, 0, run)
+	t.runs = t.runs[:min(len(t.runs), maxRuns)]
+	return summary, err
+}
+
+// Syncing returns whether the profile is being synced.
+func (t *Tracker) Syncing() bool {
+	return t.syncing.Load()
+}
+
+// Runs returns the last runs, the most recent first.
+func (t *Tracker) Runs() []Run {
+	t.runsMu.Lock()
+	defer t.runsMu.Unlock()
+	return slices.Clone(t.runs)
+}
+
+// Do runs f once the other operations on the profile are done,
+// including the ones of the other processes. It gives up waiting for
+// the other processes once ctx is done.
+func (t *Tracker) Do(ctx context.Context, f func() error) (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+
+	lock, err := fileutil.LockContext(ctx, t.Profile.lockPath())
+	if err != nil {
+		return fmt.Errorf("lock profile: %w", err)
+	}
+	defer errutil.RunAndSetError(lock.Unlock, &err, "unlock profile")
+	if err = t.reload(ctx); err != nil {
+		return err
+	}
+	return f()
+}
+
+// TryDo runs f if no other operation is running on the profile.
+// It returns false if f didn't run.
+// The files of the profile are not locked, so f must not change
+// anything.
+func (t *Tracker) TryDo(f func()) bool {
+	if !t.mu.TryLock() {
+		return false
+	}
+	defer t.mu.Unlock()
+	if t.closed {
+		return false
+	}
+	f()
+	return true
+}
+
+// Close waits for the operations in progress on the profile to be
+// done, and prevents new ones from starting. The operations started
+// after Close return ErrClosed.
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+}
+
+// reload reloads the files of the profile, since they may have been
+// changed by another process since the last time they were used.
+// The files recovered from their backup are reported.
+func (t *Tracker) reload(ctx context.Context) error {
+	err := t.Trakt.LoadAuthFile()
+	if err != nil && !errors.Is(err, fileutil.ErrRecovered) {
+		return fmt.Errorf("reload trakt auth: %w", err)
+	}
+	t.reportRecovery(ctx, err)
+	err = t.Activity.Reload()
+	if err != nil && !errors.Is(err, fileutil.ErrRecovered) {
+		return fmt.Errorf("reload activity: %w", err)
+	}
+	t.reportRecovery(ctx, err)
+	return nil
+}
+
+// reportRecovery reports the files that got recovered from their
+// backup, if any.
+func (t *Tracker) reportRecovery(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	t.Slack.SendMessage(ctx, "Some files were corrupted and got restored from their backup, the recent changes may be lost: "+err.Error())
+}
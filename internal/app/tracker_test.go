@@ -0,0 +1,37 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncInProgress(t *testing.T) {
+	t.Parallel()
+
+	tracker := new(Tracker)
+	tracker.syncing.Store(true)
+
+	_, err := tracker.Sync(t.Context())
+	require.ErrorIs(t, err, ErrSyncInProgress)
+	require.True(t, tracker.Syncing(), "the sync in progress should not be marked as done")
+	require.Empty(t, tracker.Runs(), "a skipped sync should not be recorded")
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	tracker := new(Tracker)
+	tracker.Close()
+
+	_, err := tracker.Sync(t.Context())
+	require.ErrorIs(t, err, ErrClosed)
+	require.False(t, tracker.Syncing())
+
+	err = tracker.Do(t.Context(), func() error {
+		t.Fatal("f should not be called once the tracker is closed")
+		return nil
+	})
+	require.ErrorIs(t, err, ErrClosed)
+	require.False(t, tracker.TryDo(func() {}))
+}
@@ -0,0 +1,98 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/Nivl/trakt-netflix/internal/errutil"
+)
+
+// BackupPath returns the path of the backup of the file at the given
+// path.
+func BackupPath(path string) string {
+	return path + ".bak"
+}
+
+// WriteFile writes data to the given path, readable by the owner only.
+//
+// The data is written to a temporary file that replaces the file once
+// it's entirely on disk, so a crash or a full disk never leaves a
+// half-written or a missing file. The previous version of the file is
+// kept as a backup, which ReadJSON uses if the file is corrupted.
+func WriteFile(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name()) // The file may already be gone
+		}
+	}()
+
+	if err = writeAndSync(tmp, data); err != nil {
+		return err
+	}
+	if err = backUp(path); err != nil {
+		return fmt.Errorf("back up the previous version: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace the file: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// backUp replaces the backup of the file at the given path by the
+// current version of the file, which is left in place.
+// The backup is a hard link to the file, or a copy if the file system
+// doesn't support hard links.
+func backUp(path string) error {
+	backup := BackupPath(path)
+	if err := os.Remove(backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove the previous backup: %w", err)
+	}
+	err := os.Link(path, backup)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return fmt.Errorf("read the file: %w", err)
+	}
+	if err = os.WriteFile(backup, data, 0o600); err != nil {
+		return fmt.Errorf("copy the file: %w", err)
+	}
+	return nil
+}
+
+// writeAndSync writes data to f, makes sure it's on disk, and closes f.
+func writeAndSync(f *os.File, data []byte) (err error) {
+	defer errutil.RunAndSetError(f.Close, &err, "close temporary file")
+
+	if _, err = f.Write(data); err != nil {
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("sync temporary file: %w", err)
+	}
+	return nil
+}
+
+// syncDir makes sure the renames done in the directory are on disk.
+func syncDir(dir string) (err error) {
+	d, err := os.Open(dir) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return fmt.Errorf("open directory: %w", err)
+	}
+	defer errutil.RunAndSetError(d.Close, &err, "close directory")
+
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("sync directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package fileutil contains methods to simplify working with files.
+package fileutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ErrRecovered is returned by ReadJSON when a file is missing or
+// corrupted, and got replaced by its backup.
+var ErrRecovered = errors.New("recovered from the backup")
+
+// ReadJSON reads the JSON file at the given path and decodes it into v.
+// If the file is missing or corrupted, the backup kept by WriteFile is
+// decoded into v instead, and an error wrapping ErrRecovered is
+// returned so the recovery can be reported.
+// v is left untouched if neither the file nor its backup exist.
+func ReadJSON(path string, v any) error {
+	err := readJSON(path, v)
+	if err == nil {
+		return nil
+	}
+
+	backupErr := readJSON(BackupPath(path), v)
+	switch {
+	case backupErr == nil:
+		return fmt.Errorf("%s %w: %w", path, ErrRecovered, err)
+	case errors.Is(err, fs.ErrNotExist) && errors.Is(backupErr, fs.ErrNotExist):
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("backup: %w", backupErr)
+	default:
+		return err
+	}
+}
+
+// readJSON reads the JSON file at the given path and decodes it into v.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return fmt.Errorf("read the file: %w", err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshal the data: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON encodes v to JSON and writes it to the given path.
+// See WriteFile.
+func WriteJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal the data: %w", err)
+	}
+	return WriteFile(path, data)
+}
@@ -0,0 +1,135 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	type data struct {
+		Name string `json:"name"`
+	}
+
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	// Reading a file that doesn't exist is a noop
+	got := data{Name: "untouched"}
+	require.NoError(t, ReadJSON(path, &got))
+	assert.Equal(t, "untouched", got.Name)
+
+	require.NoError(t, WriteJSON(path, data{Name: "written"}))
+	require.NoError(t, ReadJSON(path, &got))
+	assert.Equal(t, "written", got.Name)
+}
+
+func TestReadJSONRecovery(t *testing.T) {
+	t.Parallel()
+
+	type data struct {
+		Name string `json:"name"`
+	}
+
+	testCases := []struct {
+		desc string
+		// corrupt damages the files after "v1" then "v2" got written
+		corrupt         func(t *testing.T, path string)
+		expectedName    string
+		expectRecovered bool
+		expectError     bool
+	}{
+		{
+			desc:            "valid file",
+			corrupt:         func(*testing.T, string) {},
+			expectedName:    "v2",
+			expectRecovered: false,
+			expectError:     false,
+		},
+		{
+			desc: "corrupted file",
+			corrupt: func(t *testing.T, path string) {
+				t.Helper()
+				require.NoError(t, os.WriteFile(path, []byte(`{"name": "v`), 0o600))
+			},
+			expectedName:    "v1",
+			expectRecovered: true,
+			expectError:     false,
+		},
+		{
+			desc: "missing file",
+			corrupt: func(t *testing.T, path string) {
+				t.Helper()
+				require.NoError(t, os.Remove(path))
+			},
+			expectedName:    "v1",
+			expectRecovered: true,
+			expectError:     false,
+		},
+		{
+			desc: "corrupted file and backup",
+			corrupt: func(t *testing.T, path string) {
+				t.Helper()
+				require.NoError(t, os.WriteFile(path, []byte(`{"name": "v`), 0o600))
+				require.NoError(t, os.WriteFile(BackupPath(path), []byte(`{"name": "v`), 0o600))
+			},
+			expectedName:    "",
+			expectRecovered: false,
+			expectError:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "data.json")
+			require.NoError(t, WriteJSON(path, data{Name: "v1"}))
+			require.NoError(t, WriteJSON(path, data{Name: "v2"}))
+			tc.corrupt(t, path)
+
+			var got data
+			err := ReadJSON(path, &got)
+			switch {
+			case tc.expectError:
+				require.Error(t, err)
+				return
+			case tc.expectRecovered:
+				require.ErrorIs(t, err, ErrRecovered, "the recovery should be reported")
+			default:
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedName, got.Name)
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data")
+	require.NoError(t, WriteFile(path, []byte("v1")))
+	require.NoError(t, WriteFile(path, []byte("v2")))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"data", "data.bak"}, names, "no temporary files should be left")
+
+	content, err := os.ReadFile(path) //nolint:gosec // G304: the path is from the test
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+	content, err = os.ReadFile(BackupPath(path)) //nolint:gosec // G304: the path is from the test
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content), "the previous version should be kept as a backup")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,65 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockPollInterval is how often LockContext tries to lock a file that
+// is locked by someone else.
+const lockPollInterval = 100 * time.Millisecond
+
+// ErrLocked is returned when trying to lock a file that is already
+// locked.
+var ErrLocked = errors.New("file already locked")
+
+// FileLock represents an exclusive lock on a file, shared between
+// processes.
+type FileLock struct {
+	f *os.File
+}
+
+// LockContext locks the file at the given path, waiting for the
+// current holder of the lock to release it, or for ctx to be done.
+// The file is created if needed.
+func LockContext(ctx context.Context, path string) (*FileLock, error) {
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+	for {
+		l, err := TryLock(path)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for the lock: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// TryLock locks the file at the given path. ErrLocked is returned if
+// the file is already locked. The file is created if needed.
+func TryLock(path string) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err = flock(f); err != nil {
+		_ = f.Close() // The lock failed, which is the error that matters
+		return nil, err
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	// Closing the file releases the lock
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("close lock file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package fileutil
+
+import "os"
+
+// flock is a noop on the platforms that don't support flock(2). Only
+// the goroutines of a single process can be kept from using the same
+// files on those platforms.
+func flock(*os.File) error {
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package fileutil
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryLock(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sync.lock")
+
+	lock, err := TryLock(path)
+	require.NoError(t, err)
+
+	// Each lock has its own file description, so the lock is also
+	// exclusive within a process
+	_, err = TryLock(path)
+	require.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, lock.Unlock())
+	lock, err = TryLock(path)
+	require.NoError(t, err, "the file should be lockable once released")
+	require.NoError(t, lock.Unlock())
+}
+
+func TestLockContext(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sync.lock")
+
+	lock, err := TryLock(path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*lockPollInterval)
+	defer cancel()
+	_, err = LockContext(ctx, path)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "the wait should stop once the context is done")
+
+	// The lock is released while we're waiting for it
+	time.AfterFunc(lockPollInterval, func() {
+		_ = lock.Unlock()
+	})
+	waited, err := LockContext(t.Context(), path)
+	require.NoError(t, err)
+	require.NoError(t, waited.Unlock())
+}
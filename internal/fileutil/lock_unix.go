@@ -0,0 +1,29 @@
+//go:build unix
+
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// flock puts an exclusive lock on f, without waiting for it to be
+// released if it's already locked.
+// The lock is released when f is closed, or when the process exits.
+func flock(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) //nolint:gosec // G115: file descriptors fit in an int
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.EWOULDBLOCK):
+			return ErrLocked
+		default:
+			return fmt.Errorf("lock file: %w", err)
+		}
+	}
+}
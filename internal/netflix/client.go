@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/pathutil"
 )
 
 // Doer is an interface that wraps the Do method of http.Client.
@@ -20,7 +23,15 @@ type Client struct {
 	HTTP             Doer
 	History          *History
 	WatchActivityURL string
-	Cookie           string
+	// ShaktiURL is the base URL of Netflix's internal API
+	ShaktiURL string
+	Cookie    string
+	// Location is the timezone in which the viewing dates are
+	// displayed. Defaults to time.Local.
+	Location *time.Location
+	// DateLayout is the layout of the viewing dates. If empty, the
+	// layout is guessed from the locale of the page.
+	DateLayout string
 }
 
 // NewClient creates a new Client for interacting with Netflix.
@@ -30,14 +41,25 @@ func NewClient(cfg Config) (*Client, error) {
 		return nil, fmt.Errorf("build watchActivityURL: %w", err)
 	}
 
-	watchHistory, err := NewHistory()
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load timezone %q: %w", cfg.Timezone, err)
+	}
+
+	if cfg.RelHistoryFilePath == "" {
+		cfg.RelHistoryFilePath = "history"
+	}
+	watchHistory, err := NewHistory(filepath.Join(pathutil.ConfigDir(), cfg.RelHistoryFilePath), cfg.Rewatches)
 	if err != nil {
 		return nil, fmt.Errorf("create history: %w", err)
 	}
 	return &Client{
 		WatchActivityURL: u,
+		ShaktiURL:        cfg.ShaktiURL,
 		Cookie:           cfg.Cookie,
 		History:          watchHistory,
+		Location:         loc,
+		DateLayout:       cfg.DateLayout,
 		HTTP: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -0,0 +1,67 @@
+package netflix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultLocale is the locale used when the page doesn't tell us which
+// one is being used, or when we don't know the locale.
+const defaultLocale = "en-US"
+
+// dateLayouts contains the layouts Netflix uses to display the viewing
+// date, indexed by locale.
+// Netflix uses the short date format of the account's locale, which
+// may or may not include a 2-digit year.
+var dateLayouts = map[string][]string{
+	"en-US": {"1/2/06", "1/2/2006"},
+	"en-GB": {"2/1/06", "2/1/2006"},
+	"en-AU": {"2/1/06", "2/1/2006"},
+	"en-IE": {"2/1/06", "2/1/2006"},
+	"en-IN": {"2/1/06", "2/1/2006"},
+	"en-NZ": {"2/1/06", "2/1/2006"},
+	"fr":    {"2/1/06", "2/1/2006"},
+	"fr-CA": {"2006-01-02"},
+	"es":    {"2/1/06", "2/1/2006"},
+	"it":    {"2/1/06", "2/1/2006"},
+	"pt":    {"2/1/06", "2/1/2006"},
+	"de":    {"2.1.06", "2.1.2006"},
+	"nl":    {"2-1-06", "2-1-2006"},
+	"ja":    {"2006/1/2", "06/1/2"},
+	"zh":    {"2006/1/2"},
+	"ko":    {"06. 1. 2.", "2006. 1. 2."},
+}
+
+// dateLayoutsForLocale returns the date layouts to use for the given
+// locale (ex. "en-US", "fr").
+// The full locale is tried first, then only the language.
+func dateLayoutsForLocale(locale string) []string {
+	if layouts, ok := dateLayouts[locale]; ok {
+		return layouts
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	if layouts, ok := dateLayouts[lang]; ok {
+		return layouts
+	}
+	return dateLayouts[defaultLocale]
+}
+
+// parseDate parses a date from the viewing activity page using
+// the provided layouts.
+// The returned date is at the start of the day in the provided location.
+func parseDate(raw string, layouts []string, loc *time.Location) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, errors.New("empty date")
+	}
+
+	for _, layout := range layouts {
+		date, err := time.ParseInLocation(layout, raw, loc)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported date format: %q", raw)
+}
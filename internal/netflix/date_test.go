@@ -0,0 +1,96 @@
+package netflix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		raw     string
+		locale  string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:   "US format",
+			raw:    "9/14/24",
+			locale: "en-US",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "UK format with a 4-digit year",
+			raw:    "14/09/2024",
+			locale: "en-GB",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "German format",
+			raw:    "14.9.24",
+			locale: "de-DE",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "Japanese format",
+			raw:    "2024/09/14",
+			locale: "ja-JP",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "Canadian French format",
+			raw:    "2024-09-14",
+			locale: "fr-CA",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "Dutch format",
+			raw:    "14-9-2024",
+			locale: "nl-NL",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "Korean format",
+			raw:    "24. 9. 14.",
+			locale: "ko-KR",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "unknown locale falls back on US",
+			raw:    "9/14/24",
+			locale: "xx",
+			want:   time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid date",
+			raw:     "14/9/24",
+			locale:  "en-US",
+			wantErr: true,
+		},
+		{
+			name:    "empty date",
+			raw:     " ",
+			locale:  "en-US",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			date, err := parseDate(tc.raw, dateLayoutsForLocale(tc.locale), time.UTC)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, date)
+		})
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/Nivl/trakt-netflix/internal/errutil"
@@ -15,6 +17,8 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+var titleLinkRegex = regexp.MustCompile(`/title/(\d+)`)
+
 // UpdateHistory Updates the viewing history from Netflix
 func (c *Client) UpdateHistory(ctx context.Context, reporter o11y.Reporter) (err error) {
 	slog.InfoContext(ctx, "Checking for new watched medias on Netflix")
@@ -26,7 +30,7 @@ func (c *Client) UpdateHistory(ctx context.Context, reporter o11y.Reporter) (err
 
 	defer errutil.RunAndSetError(res.Body.Close, &err, "close response body")
 	defer errutil.RunAndSetError(func() error {
-		_, err = io.Copy(io.Discard, res.Body)
+		_, err := io.Copy(io.Discard, res.Body)
 		return err
 	}, &err, "empty response body")
 
@@ -39,21 +43,76 @@ func (c *Client) UpdateHistory(ctx context.Context, reporter o11y.Reporter) (err
 		return fmt.Errorf("parsing HTML: %w", err)
 	}
 
-	newList := make([]string, 0, HistorySize)
+	locale := pageLocale(doc)
+	layouts := c.dateLayouts(locale)
+	// The shakti API doesn't tell us the locale, so we keep the one of
+	// the page to parse the titles it returns
+	c.History.Locale = locale
+	loc := c.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	newList := make([]HistoryItem, 0, HistorySize)
 	for _, s := range doc.Find(".retableRow").EachIter() {
-		newList = append(newList, s.Find(".title").Find("a").Text())
+		link := s.Find(".title").Find("a")
+		title := cleanupTitle(link.Text(), locale)
+		rawDate := s.Find(".date").Text()
+		date, err := parseDate(rawDate, layouts, loc)
+		if err != nil {
+			// We don't want to block the sync because of a date, the
+			// activity will be marked as watched at the time of the sync
+			slog.WarnContext(ctx, "could not parse the viewing date", "title", title, "date", rawDate, "error", err.Error())
+		}
+		newList = append(newList, HistoryItem{
+			VideoID: videoID(link.AttrOr("href", "")),
+			Title:   title,
+			Date:    date,
+		})
 	}
 
 	// we reverse the list to have the oldest entries first, and
 	// newest last
 	slices.Reverse(newList)
-	for _, title := range newList {
-		c.History.Push(ctx, cleanupString(title), reporter)
+	c.History.setPage(newList)
+	for _, item := range newList {
+		c.History.Push(ctx, item, reporter)
 	}
 
 	return nil
 }
 
+// videoID extracts the Netflix video ID from a link to a title
+// (ex. "/title/81677518").
+// Returns an empty string if the link doesn't contain an ID.
+func videoID(href string) string {
+	matches := titleLinkRegex.FindStringSubmatch(href)
+	if len(matches) != 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// dateLayouts returns the layouts to use to parse the viewing dates of
+// a page in the given locale.
+func (c *Client) dateLayouts(locale string) []string {
+	if c.DateLayout != "" {
+		return []string{c.DateLayout}
+	}
+	return dateLayoutsForLocale(locale)
+}
+
+// pageLocale returns the locale used by the viewing activity page.
+func pageLocale(doc *goquery.Document) string {
+	if lang := doc.Find(`[data-uia="loc"]`).AttrOr("lang", ""); lang != "" {
+		return lang
+	}
+	if lang := doc.Find("html").AttrOr("lang", ""); lang != "" {
+		return lang
+	}
+	return defaultLocale
+}
+
 // cleanupString normalizes whitespace in a string.
 //
 // TODO(melvin): There's probably a cleaner way to do that.
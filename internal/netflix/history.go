@@ -3,89 +3,291 @@ package netflix
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
+	"time"
 
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
 	"github.com/Nivl/trakt-netflix/internal/o11y"
-	"github.com/Nivl/trakt-netflix/internal/pathutil"
 )
 
+// historyVersion is the current version of the history file format.
+//
+// Version history:
+//   - 0: Items are the display titles, deduped by title.
+//   - 1: Items are HistoryItem, deduped by video ID and date.
+const historyVersion = 1
+
+// HistoryItem represents an entry of the Netflix viewing activity.
+type HistoryItem struct {
+	// VideoID is the Netflix ID of the video. Empty for items migrated
+	// from an old history file.
+	VideoID string `json:"videoId,omitempty"`
+	// Title is the title as displayed by Netflix.
+	Title string `json:"title"`
+	// Date is the day the video was watched on. Zero for items migrated
+	// from an old history file.
+	Date time.Time `json:"date,omitzero"`
+}
+
+// Key returns the identity of the item in the history.
+// Items are identified by their video ID and viewing date, or by their
+// title if the video ID is unknown.
+func (i HistoryItem) Key() string {
+	id := i.VideoID
+	if id == "" {
+		id = i.Title
+	}
+	if i.Date.IsZero() {
+		return id
+	}
+	return id + "@" + i.Date.Format(time.DateOnly)
+}
+
+// SameViewing returns whether both items are the same title, watched
+// on the same day. This is used to compare items that don't have a
+// video ID.
+func (i HistoryItem) SameViewing(other HistoryItem) bool {
+	return i.Title == other.Title && i.Date.Format(time.DateOnly) == other.Date.Format(time.DateOnly)
+}
+
+// isLegacy returns whether the item comes from an old history file
+// that didn't store the video IDs.
+func (i HistoryItem) isLegacy() bool {
+	return i.VideoID == "" && i.Date.IsZero()
+}
+
 // History represents the viewing history of a user.
 type History struct {
-	ItemsSearch map[string]struct{} `json:"search"`
-	Items       []string            `json:"items"`
-	NewActivity []*WatchActivity    `json:"-"`
+	ItemsSearch map[string]struct{}
+	Items       []HistoryItem
+	NewActivity []*WatchActivity
+	// TrackRewatches controls whether a new viewing date for a video
+	// that is already in the history should be treated as a new play.
+	TrackRewatches bool
+	// Locale is the locale the titles are displayed in (ex. "fr-FR").
+	// Empty if unknown.
+	Locale string
+	// Overridden returns whether the user mapped the given title to a
+	// Trakt media, in which case a title that can't be parsed doesn't
+	// need to be reported. Nil if no titles are overridden.
+	Overridden func(title string) bool
+
+	// path is the path of the history file on disk
+	path string
+	// page contains the keys of the items listed on the last viewing
+	// activity page we fetched.
+	page map[string]struct{}
+}
+
+// historyFile represents the content of the history file on disk.
+type historyFile struct {
+	Version int             `json:"version"`
+	Items   json.RawMessage `json:"items"`
 }
 
 // NewHistory creates a new History instance, and loads the initial
-// data stored on disk.
-func NewHistory() (*History, error) {
+// data stored on disk at the given path.
+func NewHistory(path string, trackRewatches bool) (*History, error) {
 	h := &History{
-		ItemsSearch: make(map[string]struct{}),
-		Items:       []string{},
-		NewActivity: []*WatchActivity{},
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: trackRewatches,
+		Locale:         "",
+		Overridden:     nil,
+		path:           path,
+		page:           map[string]struct{}{},
 	}
+	// The recovery of a file is reported when the files get reloaded
+	// before being used
 	err := h.Load()
-	if err != nil {
+	if err != nil && !errors.Is(err, fileutil.ErrRecovered) {
 		return nil, fmt.Errorf("load history: %w", err)
 	}
 	return h, nil
 }
 
 // Has checks if the history contains a specific item.
-func (h *History) Has(item string) bool {
-	_, ok := h.ItemsSearch[item]
+func (h *History) Has(item HistoryItem) bool {
+	_, ok := h.ItemsSearch[item.Key()]
 	return ok
 }
 
-// Push adds a new item to the history.
-func (h *History) Push(ctx context.Context, item string, r o11y.Reporter) {
+// HasViewing checks if the history contains the same title, watched on
+// the same day as the given item.
+func (h *History) HasViewing(item HistoryItem) bool {
+	return slices.ContainsFunc(h.Items, item.SameViewing)
+}
+
+// Push records a new item from the viewing activity.
+// Items that are not in the history yet are added to the new activity.
+// They only get added to the history once committed.
+func (h *History) Push(ctx context.Context, item HistoryItem, r o11y.Reporter) {
+	if h.Has(item) {
+		return
+	}
+
+	// Items coming from an old history file only have a title.
+	// If we find one, we upgrade it instead of treating the item as
+	// a new activity, to avoid re-syncing everything after an update.
+	if i := h.legacyIndex(item.Title); i >= 0 {
+		delete(h.ItemsSearch, h.Items[i].Key())
+		h.Items[i] = item
+		h.ItemsSearch[item.Key()] = struct{}{}
+		return
+	}
+
+	// Netflix only lists a video once, with the date of its last
+	// viewing. If a video we already have comes back with a new date,
+	// it means it got watched again.
+	isRewatch := h.hasVideo(item)
+	if isRewatch && !h.TrackRewatches {
+		// We still commit the new play so it isn't seen as new on the
+		// next run
+		h.Commit(item)
+		return
+	}
+
+	// The overrides are consulted before parsing the title, since
+	// they already tell what the title is
+	if h.Overridden != nil && h.Overridden(item.Title) {
+		r = nil
+	}
+	activity := ParseLocalizedTitle(ctx, item.Title, h.Locale, r)
+	activity.RawTitle = item.Title
+	activity.VideoID = item.VideoID
+	activity.Date = item.Date
+	activity.IsRewatch = isRewatch
+	h.NewActivity = append(h.NewActivity, activity)
+}
+
+// Commit adds an item to the history, meaning it got synced and should
+// not be treated as a new activity anymore.
+// When the history is full, the items are removed in the order they
+// got committed. The items still listed on the viewing activity page
+// are kept, since they would look new again on the next run.
+func (h *History) Commit(item HistoryItem) {
 	if h.Has(item) {
 		return
 	}
 
-	if len(h.Items) >= HistorySize {
-		delete(h.ItemsSearch, h.Items[0])
-		h.Items = h.Items[1:]
+	for len(h.Items) >= HistorySize {
+		i := slices.IndexFunc(h.Items, func(i HistoryItem) bool {
+			_, onPage := h.page[i.Key()]
+			return !onPage
+		})
+		if i < 0 {
+			// The page lists more items than the history can hold.
+			// The history will shrink once they're gone from the page.
+			break
+		}
+		delete(h.ItemsSearch, h.Items[i].Key())
+		h.Items = slices.Delete(h.Items, i, i+1)
 	}
 
 	h.Items = append(h.Items, item)
-	h.ItemsSearch[item] = struct{}{}
-	h.NewActivity = append(h.NewActivity, ParseTitle(ctx, item, r))
+	h.ItemsSearch[item.Key()] = struct{}{}
 }
 
-// Write saves the history to disk.
-func (h *History) Write() error {
-	dataFilePath := filepath.Join(pathutil.ConfigDir(), "history")
+// setPage records the items listed on the viewing activity page.
+func (h *History) setPage(items []HistoryItem) {
+	h.page = make(map[string]struct{}, len(items))
+	for _, item := range items {
+		h.page[item.Key()] = struct{}{}
+	}
+}
 
-	data, err := json.Marshal(h)
-	if err != nil {
-		return fmt.Errorf("marshal the data: %w", err)
+// hasVideo checks if the history contains the video of the given item,
+// regardless of when it was watched.
+func (h *History) hasVideo(item HistoryItem) bool {
+	for _, i := range h.Items {
+		if item.VideoID != "" && i.VideoID == item.VideoID {
+			return true
+		}
+		if item.VideoID == "" && i.Title == item.Title {
+			return true
+		}
 	}
-	return os.WriteFile(dataFilePath, data, 0o600)
+	return false
 }
 
-// Load loads the history from disk.
-func (h *History) Load() error {
-	dataFilePath := filepath.Join(pathutil.ConfigDir(), "history")
+// legacyIndex returns the index of the legacy item with the given
+// title, or -1 if there is none.
+func (h *History) legacyIndex(title string) int {
+	for i, item := range h.Items {
+		if item.isLegacy() && item.Title == title {
+			return i
+		}
+	}
+	return -1
+}
 
-	// TODO(melvin): Use something more secure than ReadFile, to avoid
-	// loading a huge file in memory.
-	data, err := os.ReadFile(dataFilePath) //nolint:gosec // G304: file inclusion via variable is what we want here
+// MarshalJSON implements the json.Marshaler interface.
+func (h *History) MarshalJSON() ([]byte, error) {
+	items, err := json.Marshal(h.Items)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+		return nil, fmt.Errorf("marshal the items: %w", err)
+	}
+	return json.Marshal(historyFile{
+		Version: historyVersion,
+		Items:   items,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// History files using an older format are migrated to the current one.
+func (h *History) UnmarshalJSON(data []byte) error {
+	var file historyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	var items []HistoryItem
+	switch file.Version {
+	case 0:
+		var titles []string
+		if err := json.Unmarshal(file.Items, &titles); err != nil {
+			return fmt.Errorf("unmarshal the legacy items: %w", err)
+		}
+		items = make([]HistoryItem, 0, len(titles))
+		for _, title := range titles {
+			items = append(items, HistoryItem{
+				VideoID: "",
+				Title:   title,
+				Date:    time.Time{},
+			})
 		}
-		return fmt.Errorf("read the file: %w", err)
+	case historyVersion:
+		if err := json.Unmarshal(file.Items, &items); err != nil {
+			return fmt.Errorf("unmarshal the items: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported history version %d", file.Version)
 	}
-	err = json.Unmarshal(data, h)
-	if err != nil {
-		return fmt.Errorf("unmarshal the data: %w", err)
+
+	h.Items = items
+	h.ItemsSearch = make(map[string]struct{}, len(items))
+	for _, item := range items {
+		h.ItemsSearch[item.Key()] = struct{}{}
 	}
 	return nil
 }
 
+// Write saves the history to disk.
+// The previous version of the history is kept as a backup.
+func (h *History) Write() error {
+	return fileutil.WriteJSON(h.path, h)
+}
+
+// Load loads the history from disk.
+// The backup of the history is used if the history is corrupted, in
+// which case an error wrapping fileutil.ErrRecovered is returned.
+func (h *History) Load() error {
+	return fileutil.ReadJSON(h.path, h)
+}
+
 // ClearNewActivity clears the new activity from the history.
 func (h *History) ClearNewActivity() {
 	h.NewActivity = []*WatchActivity{}
@@ -0,0 +1,311 @@
+package netflix
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryMigratesLegacyFile(t *testing.T) {
+	t.Parallel()
+
+	legacy := `{"search":{"Pain Hustlers":{}},"items":["Pain Hustlers"]}`
+
+	h := &History{
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
+		path:           "",
+		page:           nil,
+	}
+	require.NoError(t, json.Unmarshal([]byte(legacy), h))
+	require.Len(t, h.Items, 1)
+	assert.Equal(t, "Pain Hustlers", h.Items[0].Title)
+	assert.Empty(t, h.Items[0].VideoID)
+
+	// The legacy item gets upgraded instead of being treated as
+	// a new activity
+	item := HistoryItem{
+		VideoID: "81614419",
+		Title:   "Pain Hustlers",
+		Date:    time.Date(2023, time.November, 22, 0, 0, 0, 0, time.UTC),
+	}
+	h.Push(t.Context(), item, nil)
+	assert.Empty(t, h.NewActivity)
+	require.Len(t, h.Items, 1)
+	assert.Equal(t, item, h.Items[0])
+	assert.True(t, h.Has(item))
+
+	// Once written, the history uses the new format
+	data, err := json.Marshal(h)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version":1,"items":[{"videoId":"81614419","title":"Pain Hustlers","date":"2023-11-22T00:00:00Z"}]}`, string(data))
+
+	reloaded := &History{
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
+		path:           "",
+		page:           nil,
+	}
+	require.NoError(t, json.Unmarshal(data, reloaded))
+	require.Len(t, reloaded.Items, 1)
+	assert.Equal(t, item.Key(), reloaded.Items[0].Key())
+	assert.True(t, reloaded.Has(item))
+}
+
+func TestHistoryPushUsesVideoIDs(t *testing.T) {
+	t.Parallel()
+
+	h := &History{
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
+		path:           "",
+		page:           nil,
+	}
+	date := time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC)
+
+	// Two different titles sharing the same display text
+	h.Push(t.Context(), HistoryItem{VideoID: "70143836", Title: "Rebecca", Date: date}, nil)
+	h.Push(t.Context(), HistoryItem{VideoID: "81002898", Title: "Rebecca", Date: date}, nil)
+	// The same title seen twice on the same day
+	h.Push(t.Context(), HistoryItem{VideoID: "81002898", Title: "Rebecca", Date: date}, nil)
+
+	require.Len(t, h.NewActivity, 3)
+	assert.Equal(t, "70143836", h.NewActivity[0].VideoID)
+	assert.Equal(t, "81002898", h.NewActivity[1].VideoID)
+	assert.Equal(t, date, h.NewActivity[1].Date)
+	assert.Empty(t, h.Items, "new activity should not be committed")
+
+	h.Commit(h.NewActivity[1].HistoryItem())
+	h.ClearNewActivity()
+	h.Push(t.Context(), HistoryItem{VideoID: "81002898", Title: "Rebecca", Date: date}, nil)
+	assert.Empty(t, h.NewActivity, "committed activity should not be new anymore")
+}
+
+func TestHistoryCommit(t *testing.T) {
+	t.Parallel()
+
+	h := &History{
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
+		path:           "",
+		page:           nil,
+	}
+
+	start := time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)
+	item := func(i int) HistoryItem {
+		return HistoryItem{
+			VideoID: strconv.Itoa(i),
+			Title:   "Title " + strconv.Itoa(i),
+			Date:    start.AddDate(0, 0, i),
+		}
+	}
+
+	// Items are evicted in commit order, not by date, since the retried
+	// items get committed after more recent ones
+	for i := 1; i < HistorySize; i++ {
+		h.Commit(item(i))
+	}
+	h.Commit(item(0))
+	require.Len(t, h.Items, HistorySize)
+
+	h.Commit(item(HistorySize))
+	require.Len(t, h.Items, HistorySize)
+	assert.False(t, h.Has(item(1)), "the first committed item should have been evicted")
+	assert.True(t, h.Has(item(0)))
+	assert.True(t, h.Has(item(HistorySize)))
+
+	// The items without a date are evicted like any other
+	undated := HistoryItem{VideoID: "undated", Title: "Undated", Date: time.Time{}}
+	h.Commit(undated)
+	assert.True(t, h.Has(undated))
+	assert.False(t, h.Has(item(2)))
+
+	// The items still listed by Netflix are never evicted
+	h.setPage([]HistoryItem{item(3), item(4)})
+	h.Commit(item(HistorySize + 1))
+	require.Len(t, h.Items, HistorySize)
+	assert.True(t, h.Has(item(3)))
+	assert.True(t, h.Has(item(4)))
+	assert.False(t, h.Has(item(5)))
+}
+
+func TestHistoryCommitFullPage(t *testing.T) {
+	t.Parallel()
+
+	h := &History{
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
+		path:           "",
+		page:           nil,
+	}
+	item := func(i int) HistoryItem {
+		return HistoryItem{VideoID: strconv.Itoa(i), Title: "Title " + strconv.Itoa(i), Date: time.Time{}}
+	}
+
+	page := make([]HistoryItem, 0, HistorySize+1)
+	for i := range HistorySize + 1 {
+		page = append(page, item(i))
+	}
+	h.setPage(page)
+	for _, i := range page {
+		h.Commit(i)
+	}
+	assert.Len(t, h.Items, HistorySize+1, "the items of the page should all be kept")
+
+	// Once the items are gone from the page, the history shrinks back
+	h.setPage([]HistoryItem{item(HistorySize + 1)})
+	h.Commit(item(HistorySize + 1))
+	require.Len(t, h.Items, HistorySize)
+	assert.False(t, h.Has(item(0)))
+	assert.False(t, h.Has(item(1)))
+	assert.True(t, h.Has(item(2)))
+}
+
+func TestHistoryPushRewatches(t *testing.T) {
+	t.Parallel()
+
+	firstWatch := HistoryItem{
+		VideoID: "81614419",
+		Title:   "Pain Hustlers",
+		Date:    time.Date(2023, time.November, 22, 0, 0, 0, 0, time.UTC),
+	}
+	rewatch := HistoryItem{
+		VideoID: "81614419",
+		Title:   "Pain Hustlers",
+		Date:    time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name           string
+		trackRewatches bool
+		wantActivity   bool
+	}{
+		{
+			name:           "rewatches are tracked",
+			trackRewatches: true,
+			wantActivity:   true,
+		},
+		{
+			name:           "rewatches are ignored",
+			trackRewatches: false,
+			wantActivity:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &History{
+				ItemsSearch:    map[string]struct{}{firstWatch.Key(): {}},
+				Items:          []HistoryItem{firstWatch},
+				NewActivity:    []*WatchActivity{},
+				TrackRewatches: tc.trackRewatches,
+				Locale:         "",
+				Overridden:     nil,
+				path:           "",
+				page:           nil,
+			}
+			h.Push(t.Context(), rewatch, nil)
+
+			if !tc.wantActivity {
+				assert.Empty(t, h.NewActivity)
+				// The new play is still recorded, to not be seen as
+				// new on the next run
+				assert.True(t, h.Has(rewatch))
+				return
+			}
+			require.Len(t, h.NewActivity, 1)
+			assert.True(t, h.NewActivity[0].IsRewatch)
+			assert.Equal(t, rewatch.Date, h.NewActivity[0].Date)
+		})
+	}
+}
+
+func TestHistoryHasViewing(t *testing.T) {
+	t.Parallel()
+
+	watched := HistoryItem{
+		VideoID: "81614419",
+		Title:   "Pain Hustlers",
+		Date:    time.Date(2023, time.November, 22, 0, 0, 0, 0, time.UTC),
+	}
+	h := &History{
+		ItemsSearch:    map[string]struct{}{watched.Key(): {}},
+		Items:          []HistoryItem{watched},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden:     nil,
+		path:           "",
+		page:           nil,
+	}
+
+	exported := HistoryItem{
+		VideoID: "",
+		Title:   "Pain Hustlers",
+		Date:    time.Date(2023, time.November, 22, 21, 30, 0, 0, time.UTC),
+	}
+	assert.True(t, h.HasViewing(exported), "the same title on the same day should be found")
+
+	exported.Date = exported.Date.AddDate(0, 0, 1)
+	assert.False(t, h.HasViewing(exported), "a viewing on another day should not be found")
+}
+
+// messages is a reporter that records the messages it gets.
+type messages []string
+
+func (m *messages) SendMessage(_ context.Context, msg string) {
+	*m = append(*m, msg)
+}
+
+func TestHistoryPushOverridden(t *testing.T) {
+	t.Parallel()
+
+	h := &History{
+		ItemsSearch:    make(map[string]struct{}),
+		Items:          []HistoryItem{},
+		NewActivity:    []*WatchActivity{},
+		TrackRewatches: true,
+		Locale:         "",
+		Overridden: func(title string) bool {
+			return title == `Slasher: The Executioner: "Soon Your Own Eyes Will See"`
+		},
+		path: "",
+		page: nil,
+	}
+	date := time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC)
+
+	var reported messages
+	h.Push(t.Context(), HistoryItem{VideoID: "80186799", Title: `Slasher: The Executioner: "Soon Your Own Eyes Will See"`, Date: date}, &reported)
+	assert.Empty(t, reported, "the overridden titles should not be reported")
+
+	h.Push(t.Context(), HistoryItem{VideoID: "80186800", Title: `Slasher: Flesh & Blood: "Family Reunion"`, Date: date}, &reported)
+	assert.Len(t, reported, 1, "the other titles should be reported")
+	assert.Len(t, h.NewActivity, 2)
+}
@@ -0,0 +1,133 @@
+package netflix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/errutil"
+)
+
+// Metadata contains the information Netflix has about a video.
+type Metadata struct {
+	// IsShow is true when the video is an episode.
+	IsShow bool `json:"isShow"`
+	// Title is the title of the movie, or the title of the show the
+	// episode belongs to.
+	Title string `json:"title"`
+	// EpisodeTitle is the title of the episode. Empty for movies.
+	EpisodeTitle string `json:"episodeTitle,omitempty"`
+	// Season is the number of the season, as displayed by Netflix.
+	// 0 for movies.
+	Season int `json:"season,omitempty"`
+	// Episode is the number of the episode in its season. 0 for
+	// movies.
+	Episode int `json:"episode,omitempty"`
+	// Runtime is the duration of the video.
+	Runtime time.Duration `json:"runtime"`
+	// Year is the release year of the movie, or of the first season
+	// of the show.
+	Year int `json:"year"`
+}
+
+// metadataJSON represents the metadata of a video, as returned by the
+// shakti API.
+type metadataJSON struct {
+	Video struct {
+		ID      int64  `json:"id"`
+		Type    string `json:"type"`
+		Title   string `json:"title"`
+		Year    int    `json:"year"`
+		Runtime int    `json:"runtime"`
+		Seasons []struct {
+			Seq      int `json:"seq"`
+			Year     int `json:"year"`
+			Episodes []struct {
+				ID      int64  `json:"id"`
+				Title   string `json:"title"`
+				Seq     int    `json:"seq"`
+				Runtime int    `json:"runtime"`
+			} `json:"episodes"`
+		} `json:"seasons"`
+	} `json:"video"`
+}
+
+// FetchMetadata returns the metadata of the video with the given ID,
+// as listed in the viewing activity.
+//
+// This uses Netflix's shakti API, which gives the season and episode
+// numbers that cannot be guessed from the title.
+func (c *Client) FetchMetadata(ctx context.Context, videoID string) (md *Metadata, err error) {
+	if videoID == "" {
+		return nil, errors.New("missing video ID")
+	}
+	query := url.Values{}
+	query.Set("movieid", videoID)
+	u, err := url.JoinPath(c.ShaktiURL, "metadata")
+	if err != nil {
+		return nil, fmt.Errorf("build metadata URL: %w", err)
+	}
+
+	res, err := c.request(ctx, u+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("make http request: %w", err)
+	}
+
+	defer errutil.RunAndSetError(res.Body.Close, &err, "close response body")
+	defer errutil.RunAndSetError(func() error {
+		_, err := io.Copy(io.Discard, res.Body)
+		return err
+	}, &err, "empty response body")
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http %d", res.StatusCode)
+	}
+
+	var data metadataJSON
+	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode the response: %w", err)
+	}
+	v := data.Video
+
+	if v.Type != "show" {
+		return &Metadata{
+			IsShow:       false,
+			Title:        cleanupString(v.Title),
+			EpisodeTitle: "",
+			Season:       0,
+			Episode:      0,
+			Runtime:      time.Duration(v.Runtime) * time.Second,
+			Year:         v.Year,
+		}, nil
+	}
+
+	// The metadata of an episode contains the whole show, so we need
+	// to look for the episode
+	for _, s := range v.Seasons {
+		for _, e := range s.Episodes {
+			if strconv.FormatInt(e.ID, 10) != videoID {
+				continue
+			}
+			year := v.Year
+			if len(v.Seasons) > 0 && v.Seasons[0].Year > 0 {
+				year = v.Seasons[0].Year
+			}
+			return &Metadata{
+				IsShow:       true,
+				Title:        cleanupString(v.Title),
+				EpisodeTitle: cleanupString(e.Title),
+				Season:       s.Seq,
+				Episode:      e.Seq,
+				Runtime:      time.Duration(e.Runtime) * time.Second,
+				Year:         year,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("episode %s not found in show %d", videoID, v.ID)
+}
@@ -0,0 +1,104 @@
+package netflix
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFetchMetadata(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		fixture string
+		videoID string
+		want    *Metadata
+		wantErr string
+	}{
+		{
+			name:    "episode",
+			fixture: "metadata_show.json",
+			videoID: "81482809",
+			want: &Metadata{
+				IsShow:       true,
+				Title:        "Alice in Borderland",
+				EpisodeTitle: "Episode 8",
+				Season:       2,
+				Episode:      8,
+				Runtime:      3301 * time.Second,
+				Year:         2020,
+			},
+			wantErr: "",
+		},
+		{
+			name:    "movie",
+			fixture: "metadata_movie.json",
+			videoID: "81614419",
+			want: &Metadata{
+				IsShow:       false,
+				Title:        "Pain Hustlers",
+				EpisodeTitle: "",
+				Season:       0,
+				Episode:      0,
+				Runtime:      7440 * time.Second,
+				Year:         2023,
+			},
+			wantErr: "",
+		},
+		{
+			name:    "episode not in the show",
+			fixture: "metadata_show.json",
+			videoID: "1",
+			want:    nil,
+			wantErr: "episode 1 not found in show 80200575",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(filepath.Join("testdata", tc.fixture))
+			require.NoError(t, err)
+
+			mockCtrl := gomock.NewController(t)
+			doer := mocks.NewMockDoer(mockCtrl)
+			doer.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/api/shakti/mre/metadata", req.URL.Path)
+				assert.Equal(t, tc.videoID, req.URL.Query().Get("movieid"))
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader(data)),
+				}, nil
+			})
+
+			c := &Client{
+				HTTP:             doer,
+				History:          nil,
+				WatchActivityURL: "",
+				ShaktiURL:        "https://www.netflix.com/api/shakti/mre",
+				Cookie:           "cookie",
+				Location:         time.UTC,
+				DateLayout:       "",
+			}
+
+			got, err := c.FetchMetadata(t.Context(), tc.videoID)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -9,4 +9,17 @@ type Config struct {
 	AccountID string `env:"ACCOUNT_ID"`
 	Cookie    string `env:"COOKIE,required"`
 	URL       string `env:"URL,default=https://www.netflix.com/viewingactivity"`
+	ShaktiURL string `env:"SHAKTI_URL,default=https://www.netflix.com/api/shakti/mre"`
+	// Timezone is the IANA name of the timezone in which the
+	// viewing dates are displayed (ex. "America/Los_Angeles").
+	Timezone string `env:"TIMEZONE,default=Local"`
+	// DateLayout is the Go layout of the viewing dates. If empty,
+	// the layout is guessed from the locale of the page.
+	DateLayout string `env:"DATE_LAYOUT"`
+	// RelHistoryFilePath is the path of the history file, relative to
+	// the config directory.
+	RelHistoryFilePath string `env:"HISTORY_FILE_REL_PATH"`
+	// Rewatches controls whether watching again something that is
+	// still in the history should add a new play on Trakt.
+	Rewatches bool `env:"REWATCHES"`
 }
@@ -13,12 +13,24 @@ import (
 var (
 	titleDefaultRegex   = regexp.MustCompile(`(.+): (.+): "(.+)"`)
 	titleShowColonRegex = regexp.MustCompile(`((.+): (.+)): ((.+): (.+)): "(.+)"`)
-	titleSeasonRegex    = regexp.MustCompile(`(.+): (((Season|Part|Class|Volume) (\d+))|(Limited Series)|(Collection)): "(.+)"`)
 	titleShortRegex     = regexp.MustCompile(`([^:]+): "([^:]+)"`)
+	seasonNumberRegex   = regexp.MustCompile(`\d+`)
 )
 
 // ParseTitle parses a Netflix title and turns it into a WatchActivity.
+// The language of the title is unknown, so the vocabulary of all the
+// supported locales is used.
 func ParseTitle(ctx context.Context, title string, reporter o11y.Reporter) *WatchActivity {
+	return ParseLocalizedTitle(ctx, title, "", reporter)
+}
+
+// ParseLocalizedTitle parses a Netflix title displayed in the given
+// locale (ex. "fr-FR"), and turns it into a WatchActivity.
+// The vocabulary of all the supported locales is used if the locale
+// is empty or unknown.
+func ParseLocalizedTitle(ctx context.Context, title, locale string, reporter o11y.Reporter) *WatchActivity {
+	rules := titleRulesForLocale(locale)
+
 	h := &WatchActivity{ //nolint:exhaustruct // The point of this function is to slowly build that object
 		Title: title,
 		// All shows have their episode names wrapped in quotes.
@@ -62,12 +74,14 @@ func ParseTitle(ctx context.Context, title string, reporter o11y.Reporter) *Watc
 	// Ex: That '90s Show: Part 2: "Friends in Low Places"
 	// Ex: Weak Hero: Class 2: "Episode 1"
 	// Ex: Love, Death & Robots: Volume 4: "Close Encounters of the Mini Kind"
-	matches = titleSeasonRegex.FindAllStringSubmatch(title, -1)
-	if len(matches) == 1 && len(matches[0]) == 9 {
+	// The markers are translated in the language of the account
+	// (ex. `Dark: Staffel 1: "Geheimnisse"`).
+	matches = rules.seasonRegex.FindAllStringSubmatch(title, -1)
+	if len(matches) == 1 && len(matches[0]) == 4 {
 		h.Title = matches[0][1]
-		h.EpisodeName = matches[0][8]
+		h.EpisodeName = matches[0][3]
 		// It's expected that it may fail if there is no season number
-		h.Season, _ = strconv.Atoi(matches[0][5])
+		h.Season, _ = strconv.Atoi(seasonNumberRegex.FindString(matches[0][2]))
 		return h
 	}
 
@@ -116,6 +130,7 @@ func ParseTitle(ctx context.Context, title string, reporter o11y.Reporter) *Watc
 	if len(matches) == 1 && len(matches[0]) == 4 {
 		h.Title = matches[0][1]
 		h.EpisodeName = matches[0][3]
+		h.Guessed = true
 
 		if reporter != nil {
 			reporter.SendMessage(ctx,
@@ -144,5 +159,6 @@ func ParseTitle(ctx context.Context, title string, reporter o11y.Reporter) *Watc
 		reporter.SendMessage(ctx, fmt.Sprintf("Potentially weird title found: %s. Assuming it's a movie.", title))
 	}
 	h.IsShow = false
+	h.Guessed = true
 	return h
 }
@@ -168,6 +168,7 @@ func TestParseTitle(t *testing.T) {
 				Title:       "Slasher",
 				EpisodeName: "Soon Your Own Eyes Will See",
 				IsShow:      true,
+				Guessed:     true,
 			},
 		},
 		{
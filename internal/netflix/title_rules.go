@@ -0,0 +1,138 @@
+package netflix
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// titleVocabulary contains the words Netflix uses to display the
+// titles of the viewing activity in a given language.
+type titleVocabulary struct {
+	// seasons contains the patterns of the season markers, as they
+	// appear between the name of the show and the name of the episode
+	// (ex. "Season 2" in `Alice in Borderland: Season 2: "Episode 8"`).
+	// The number of the season, if any, is the only number of the
+	// marker.
+	seasons []string
+	// punctuation contains pairs of strings to replace, to turn the
+	// punctuation of the language into the one ParseTitle expects
+	// (`: ` between the parts of the title, and `"` around the name
+	// of the episode).
+	punctuation []string
+}
+
+// titleVocabularies contains the vocabulary of the titles, indexed by
+// locale.
+var titleVocabularies = map[string]titleVocabulary{
+	"en": {
+		seasons:     []string{`Season \d+`, `Part \d+`, `Class \d+`, `Volume \d+`, `Limited Series`, `Collection`},
+		punctuation: []string{},
+	},
+	"fr": {
+		seasons: []string{`Saison \d+`, `Partie \d+`, `Volume \d+`, `Classe \d+`, `Série limitée`, `Mini-série`, `Minisérie`, `Collection`},
+		// Ex. `Lupin : Partie 2 : « Chapitre 6 »`
+		punctuation: []string{" : ", ": ", "« ", `"`, " »", `"`, "«", `"`, "»", `"`},
+	},
+	"de": {
+		seasons: []string{`Staffel \d+`, `Teil \d+`, `Volume \d+`, `Klasse \d+`, `Miniserie`, `Limitierte Serie`, `Sammlung`, `Kollektion`},
+		// Ex. `Dark: Staffel 1: „Geheimnisse“`
+		punctuation: []string{"„", `"`, "“", `"`, "»", `"`, "«", `"`},
+	},
+	"es": {
+		seasons:     []string{`Temporada \d+`, `Parte \d+`, `Volumen \d+`, `Clase \d+`, `Serie limitada`, `Miniserie`, `Colección`},
+		punctuation: []string{"« ", `"`, " »", `"`, "«", `"`, "»", `"`, "“", `"`, "”", `"`},
+	},
+	"pt": {
+		seasons:     []string{`Temporada \d+`, `Parte \d+`, `Volume \d+`, `Classe \d+`, `Série limitada`, `Minissérie`, `Coleção`},
+		punctuation: []string{"« ", `"`, " »", `"`, "«", `"`, "»", `"`, "“", `"`, "”", `"`},
+	},
+	"it": {
+		seasons:     []string{`Stagione \d+`, `Parte \d+`, `Volume \d+`, `Classe \d+`, `Serie limitata`, `Miniserie`, `Collezione`},
+		punctuation: []string{"« ", `"`, " »", `"`, "«", `"`, "»", `"`, "“", `"`, "”", `"`},
+	},
+	"ja": {
+		seasons: []string{`シーズン\s*\d+`, `パート\s*\d+`, `Vol\.\s*\d+`, `リミテッドシリーズ`, `コレクション`},
+		// Ex. `イカゲーム: シーズン1: 「だるまさんがころんだ」`
+		punctuation: []string{"： ", ": ", "：", ": ", "「", `"`, "」", `"`},
+	},
+	"ko": {
+		seasons:     []string{`시즌\s*\d+`, `파트\s*\d+`, `리미티드 시리즈`, `컬렉션`},
+		punctuation: []string{"“", `"`, "”", `"`},
+	},
+}
+
+// titleRules contains everything needed to parse the titles of a
+// given locale.
+type titleRules struct {
+	seasonRegex *regexp.Regexp
+	punctuation *strings.Replacer
+}
+
+var (
+	// titleRulesByLocale contains the rules of each locale. They
+	// always include the English vocabulary, since Netflix doesn't
+	// translate everything.
+	titleRulesByLocale = newTitleRulesByLocale()
+	// anyLocaleTitleRules contains the rules to use when the locale
+	// is unknown. It contains the vocabulary of all the locales.
+	anyLocaleTitleRules = newTitleRules(slices.Collect(maps.Values(titleVocabularies))...)
+)
+
+// newTitleRulesByLocale returns the rules of each locale of
+// titleVocabularies.
+func newTitleRulesByLocale() map[string]*titleRules {
+	rules := make(map[string]*titleRules, len(titleVocabularies))
+	for locale, v := range titleVocabularies {
+		rules[locale] = newTitleRules(v, titleVocabularies["en"])
+	}
+	return rules
+}
+
+// newTitleRules returns the rules parsing the titles using any of
+// the provided vocabularies.
+func newTitleRules(vocabularies ...titleVocabulary) *titleRules {
+	seasons := []string{}
+	pairs := [][2]string{}
+	for _, v := range vocabularies {
+		seasons = append(seasons, v.seasons...)
+		for i := 0; i+1 < len(v.punctuation); i += 2 {
+			pairs = append(pairs, [2]string{v.punctuation[i], v.punctuation[i+1]})
+		}
+	}
+	// The replacer picks the first matching pair, so the longest
+	// strings need to go first (ex. "« " before "«")
+	slices.SortStableFunc(pairs, func(a, b [2]string) int {
+		return len(b[0]) - len(a[0])
+	})
+	punctuation := make([]string, 0, len(pairs)*2)
+	for _, p := range pairs {
+		punctuation = append(punctuation, p[0], p[1])
+	}
+
+	return &titleRules{
+		// Format is `<Show Name>: <Season marker>: "<Episode Name>"`
+		seasonRegex: regexp.MustCompile(`(.+): (` + strings.Join(seasons, "|") + `): "(.+)"`),
+		punctuation: strings.NewReplacer(punctuation...),
+	}
+}
+
+// titleRulesForLocale returns the rules to parse the titles of the
+// given locale (ex. "fr-FR", "fr").
+// All the known rules are used if the locale is empty or unknown.
+func titleRulesForLocale(locale string) *titleRules {
+	lang, _, _ := strings.Cut(locale, "-")
+	if rules, ok := titleRulesByLocale[strings.ToLower(lang)]; ok {
+		return rules
+	}
+	return anyLocaleTitleRules
+}
+
+// cleanupTitle normalizes the whitespace and the punctuation of a
+// title displayed in the given locale, so it can be parsed by
+// ParseTitle.
+func cleanupTitle(title, locale string) string {
+	title = titleRulesForLocale(locale).punctuation.Replace(cleanupString(title))
+	return cleanupString(title)
+}
@@ -0,0 +1,189 @@
+package netflix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLocalizedTitle(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		title       string
+		locale      string
+		wantTitle   string
+		wantEpisode string
+		wantSeason  int
+		wantIsShow  bool
+	}{
+		{
+			name:        "French season",
+			title:       "Arcane\u202f: Saison\u00a02\u202f: «\u00a0Le Prix du pouvoir\u00a0»",
+			locale:      "fr-FR",
+			wantTitle:   "Arcane",
+			wantEpisode: "Le Prix du pouvoir",
+			wantSeason:  2,
+			wantIsShow:  true,
+		},
+		{
+			name:        "French part",
+			title:       "Lupin : Partie 2 : « Chapitre 6 »",
+			locale:      "fr",
+			wantTitle:   "Lupin",
+			wantEpisode: "Chapitre 6",
+			wantSeason:  2,
+			wantIsShow:  true,
+		},
+		{
+			name:        "French limited series",
+			title:       "Le Jeu de la dame : Mini-série : « Ouvertures »",
+			locale:      "fr-CA",
+			wantTitle:   "Le Jeu de la dame",
+			wantEpisode: "Ouvertures",
+			wantSeason:  0,
+			wantIsShow:  true,
+		},
+		{
+			name:        "French movie",
+			title:       "Astérix : Le Domaine des dieux",
+			locale:      "fr-FR",
+			wantTitle:   "Astérix: Le Domaine des dieux",
+			wantEpisode: "",
+			wantSeason:  0,
+			wantIsShow:  false,
+		},
+		{
+			name:        "German season",
+			title:       "Dark: Staffel 1: „Geheimnisse“",
+			locale:      "de-DE",
+			wantTitle:   "Dark",
+			wantEpisode: "Geheimnisse",
+			wantSeason:  1,
+			wantIsShow:  true,
+		},
+		{
+			name:        "German part",
+			title:       "Haus des Geldes: Teil 3: „Wir sind zurück“",
+			locale:      "de-AT",
+			wantTitle:   "Haus des Geldes",
+			wantEpisode: "Wir sind zurück",
+			wantSeason:  3,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Spanish part",
+			title:       `La casa de papel: Parte 5: "Episodio 1"`,
+			locale:      "es-ES",
+			wantTitle:   "La casa de papel",
+			wantEpisode: "Episodio 1",
+			wantSeason:  5,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Spanish season",
+			title:       `Cien años de soledad: Temporada 1: "Episodio 3"`,
+			locale:      "es-MX",
+			wantTitle:   "Cien años de soledad",
+			wantEpisode: "Episodio 3",
+			wantSeason:  1,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Portuguese season",
+			title:       `Sintonia: Temporada 4: "Episódio 1"`,
+			locale:      "pt-BR",
+			wantTitle:   "Sintonia",
+			wantEpisode: "Episódio 1",
+			wantSeason:  4,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Italian season",
+			title:       `Suburra: Stagione 3: "Episodio 1"`,
+			locale:      "it-IT",
+			wantTitle:   "Suburra",
+			wantEpisode: "Episodio 1",
+			wantSeason:  3,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Japanese season",
+			title:       "イカゲーム: シーズン1: 「だるまさんがころんだ」",
+			locale:      "ja-JP",
+			wantTitle:   "イカゲーム",
+			wantEpisode: "だるまさんがころんだ",
+			wantSeason:  1,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Japanese full-width colons",
+			title:       "今際の国のアリス：シーズン2：「エピソード8」",
+			locale:      "ja",
+			wantTitle:   "今際の国のアリス",
+			wantEpisode: "エピソード8",
+			wantSeason:  2,
+			wantIsShow:  true,
+		},
+		{
+			name:        "Korean season",
+			title:       `오징어 게임: 시즌 2: "트릭 오어 트릿"`,
+			locale:      "ko-KR",
+			wantTitle:   "오징어 게임",
+			wantEpisode: "트릭 오어 트릿",
+			wantSeason:  2,
+			wantIsShow:  true,
+		},
+		{
+			name:        "English markers are always supported",
+			title:       `Alice in Borderland: Season 2: "Episode 8"`,
+			locale:      "fr-FR",
+			wantTitle:   "Alice in Borderland",
+			wantEpisode: "Episode 8",
+			wantSeason:  2,
+			wantIsShow:  true,
+		},
+		{
+			name:        "markers of other locales are not used",
+			title:       `Dark: Staffel 1: "Geheimnisse"`,
+			locale:      "fr-FR",
+			wantTitle:   "Dark",
+			wantEpisode: "Geheimnisse",
+			wantSeason:  0,
+			wantIsShow:  true,
+		},
+		{
+			name:        "unknown locales use all the markers",
+			title:       "Dark: Staffel 1: „Geheimnisse“",
+			locale:      "",
+			wantTitle:   "Dark",
+			wantEpisode: "Geheimnisse",
+			wantSeason:  1,
+			wantIsShow:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := ParseLocalizedTitle(t.Context(), cleanupTitle(tc.title, tc.locale), tc.locale, nil)
+			assert.Equal(t, tc.wantTitle, res.Title)
+			assert.Equal(t, tc.wantEpisode, res.EpisodeName)
+			assert.Equal(t, tc.wantSeason, res.Season)
+			assert.Equal(t, tc.wantIsShow, res.IsShow)
+		})
+	}
+}
+
+func TestCleanupTitle(t *testing.T) {
+	t.Parallel()
+
+	// The titles of the English pages are stored in the history the way
+	// they always were, so the old history files can still be upgraded
+	for _, title := range []string{"Pain Hustlers", "  Pain \t Hustlers ", `Dark: Season 1: "Secrets" `} {
+		assert.Equal(t, cleanupString(title), cleanupTitle(title, "en-US"), title)
+	}
+	assert.Equal(t, `Lupin: Partie 2: "Chapitre 6"`, cleanupTitle("Lupin : Partie 2 : « Chapitre 6 »", "fr-FR"))
+}
@@ -0,0 +1,90 @@
+package netflix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/errutil"
+)
+
+// ViewingActivityPageSize is the number of items to request per page
+// of viewing activity.
+const ViewingActivityPageSize = 100
+
+// viewingActivityPage represents a page of the viewing activity, as
+// returned by the shakti API.
+type viewingActivityPage struct {
+	Page        int              `json:"page"`
+	Size        int              `json:"size"`
+	Total       int              `json:"vhSize"`
+	ViewedItems []viewedItemJSON `json:"viewedItems"`
+}
+
+// viewedItemJSON represents an item of the viewing activity, as
+// returned by the shakti API.
+type viewedItemJSON struct {
+	MovieID int64 `json:"movieID"`
+	// Title is formatted the same way as on the viewing activity page
+	// (ex. `Alice in Borderland: Season 2: "Episode 8"`)
+	Title string `json:"title"`
+	// Date is the time of the viewing, in milliseconds since epoch.
+	Date int64 `json:"date"`
+}
+
+// FetchViewingActivity returns a page of the viewing activity, with
+// the newest items first. The first page is 0.
+// An empty list is returned once we're past the last page.
+//
+// Contrary to the viewing activity page that only returns the last
+// items, this uses Netflix's shakti API that gives access to the whole
+// viewing activity. The dates returned contain the time of the viewing.
+func (c *Client) FetchViewingActivity(ctx context.Context, page int) (items []HistoryItem, err error) {
+	query := url.Values{}
+	query.Set("pg", strconv.Itoa(page))
+	query.Set("pgSize", strconv.Itoa(ViewingActivityPageSize))
+	u, err := url.JoinPath(c.ShaktiURL, "viewingactivity")
+	if err != nil {
+		return nil, fmt.Errorf("build viewing activity URL: %w", err)
+	}
+
+	res, err := c.request(ctx, u+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("make http request: %w", err)
+	}
+
+	defer errutil.RunAndSetError(res.Body.Close, &err, "close response body")
+	defer errutil.RunAndSetError(func() error {
+		_, err := io.Copy(io.Discard, res.Body)
+		return err
+	}, &err, "empty response body")
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http %d", res.StatusCode)
+	}
+
+	var data viewingActivityPage
+	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode the response: %w", err)
+	}
+
+	loc := c.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	items = make([]HistoryItem, 0, len(data.ViewedItems))
+	for _, item := range data.ViewedItems {
+		items = append(items, HistoryItem{
+			VideoID: strconv.FormatInt(item.MovieID, 10),
+			Title:   cleanupTitle(item.Title, c.History.Locale),
+			Date:    time.UnixMilli(item.Date).In(loc),
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,172 @@
+package netflix
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns of the ViewingActivity.csv file that we need
+const (
+	csvColumnProfile          = "Profile Name"
+	csvColumnStartTime        = "Start Time"
+	csvColumnDuration         = "Duration"
+	csvColumnTitle            = "Title"
+	csvColumnSupplementalType = "Supplemental Video Type"
+)
+
+// csvStartTimeLayout is the layout of the Start Time column. Times
+// are in UTC.
+const csvStartTimeLayout = time.DateTime
+
+// ViewingActivityCSVFilter contains the rules used to select the rows
+// of a ViewingActivity.csv file.
+type ViewingActivityCSVFilter struct {
+	// Profile is the name of the profile to import. Can be empty if
+	// the file only contains one profile.
+	Profile string
+	// MinDuration is the minimum duration of a viewing, used to skip
+	// partial views.
+	MinDuration time.Duration
+	// Since and Until restrict the import to the viewings that started
+	// at or after Since, and before Until. Zero means no limit.
+	Since time.Time
+	Until time.Time
+}
+
+// ReadViewingActivityCSV parses the ViewingActivity.csv file contained
+// in Netflix's "Download your personal information" export, and returns
+// the matching rows, with the oldest first.
+// Trailers and other supplemental videos are skipped.
+//
+// The export doesn't contain the video IDs, and the episode names are
+// not wrapped in quotes. To make the titles look like the ones of the
+// viewing activity page, everything after the season is considered to
+// be the episode name.
+func ReadViewingActivityCSV(r io.Reader, filter ViewingActivityCSVFilter, loc *time.Location) ([]HistoryItem, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// The file starts with a BOM
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+	for _, name := range []string{csvColumnProfile, csvColumnStartTime, csvColumnDuration, csvColumnTitle, csvColumnSupplementalType} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+
+	items := []HistoryItem{}
+	profiles := map[string]struct{}{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read line %d: %w", line, err)
+		}
+
+		profile := row[columns[csvColumnProfile]]
+		profiles[profile] = struct{}{}
+		if filter.Profile != "" && profile != filter.Profile {
+			continue
+		}
+		if row[columns[csvColumnSupplementalType]] != "" {
+			continue
+		}
+
+		duration, err := parseCSVDuration(row[columns[csvColumnDuration]])
+		if err != nil {
+			return nil, fmt.Errorf("parse duration of line %d: %w", line, err)
+		}
+		if duration < filter.MinDuration {
+			continue
+		}
+
+		date, err := time.ParseInLocation(csvStartTimeLayout, row[columns[csvColumnStartTime]], time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("parse start time of line %d: %w", line, err)
+		}
+		if date.Before(filter.Since) || (!filter.Until.IsZero() && !date.Before(filter.Until)) {
+			continue
+		}
+
+		items = append(items, HistoryItem{
+			VideoID: "",
+			Title:   csvTitle(cleanupTitle(row[columns[csvColumnTitle]], "")),
+			Date:    date.In(loc),
+		})
+	}
+
+	if filter.Profile == "" && len(profiles) > 1 {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("the file contains multiple profiles, one must be selected: %s", strings.Join(names, ", "))
+	}
+	if filter.Profile != "" && len(profiles) > 0 {
+		if _, ok := profiles[filter.Profile]; !ok {
+			return nil, fmt.Errorf("profile %q not found", filter.Profile)
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b HistoryItem) int {
+		return a.Date.Compare(b.Date)
+	})
+	return items, nil
+}
+
+// parseCSVDuration parses a duration formatted as HH:MM:SS.
+func parseCSVDuration(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// csvTitle converts a title of the CSV export into the format used by
+// the viewing activity page, so it can be parsed by ParseTitle.
+//
+// Ex: `Alice in Borderland: Season 2: Episode 8`
+// becomes `Alice in Borderland: Season 2: "Episode 8"`
+//
+// Movies containing 2 colons or more will be treated as shows.
+func csvTitle(title string) string {
+	parts := strings.Split(title, ": ")
+	if len(parts) < 3 {
+		return title
+	}
+
+	// Show with a colon in its name like "Squid Game: The Challenge".
+	// Format is `<Show: Name>: <Show: Name>: <Episode Name>`
+	episodeStart := 2
+	if len(parts) >= 5 && parts[0] == parts[2] && parts[1] == parts[3] {
+		episodeStart = 4
+	}
+	return strings.Join(parts[:episodeStart], ": ") + `: "` + strings.Join(parts[episodeStart:], ": ") + `"`
+}
@@ -0,0 +1,132 @@
+package netflix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadViewingActivityCSV(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		filter  ViewingActivityCSVFilter
+		want    []HistoryItem
+		wantErr string
+	}{
+		{
+			name: "profile and duration",
+			filter: ViewingActivityCSVFilter{
+				Profile:     "Melvin",
+				MinDuration: 5 * time.Minute,
+				Since:       time.Time{},
+				Until:       time.Time{},
+			},
+			want: []HistoryItem{
+				{
+					VideoID: "",
+					Title:   `Squid Game: The Challenge: Squid Game: The Challenge: "Red Light Green Light"`,
+					Date:    time.Date(2024, time.September, 10, 3, 0, 0, 0, time.UTC).In(loc),
+				},
+				{
+					VideoID: "",
+					Title:   "Pain Hustlers",
+					Date:    time.Date(2024, time.September, 11, 4, 0, 0, 0, time.UTC).In(loc),
+				},
+				{
+					VideoID: "",
+					Title:   `Alice in Borderland: Season 2: "Episode 8"`,
+					Date:    time.Date(2024, time.September, 14, 18, 12, 5, 0, time.UTC).In(loc),
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "dates",
+			filter: ViewingActivityCSVFilter{
+				Profile:     "Melvin",
+				MinDuration: 5 * time.Minute,
+				Since:       time.Date(2024, time.September, 10, 0, 0, 0, 0, loc),
+				Until:       time.Date(2024, time.September, 14, 0, 0, 0, 0, loc),
+			},
+			want: []HistoryItem{
+				{
+					VideoID: "",
+					Title:   "Pain Hustlers",
+					Date:    time.Date(2024, time.September, 11, 4, 0, 0, 0, time.UTC).In(loc),
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "other profile",
+			filter: ViewingActivityCSVFilter{
+				Profile:     "Guest",
+				MinDuration: 0,
+				Since:       time.Time{},
+				Until:       time.Time{},
+			},
+			want: []HistoryItem{
+				{
+					VideoID: "",
+					Title:   "Rebel Moon — Part One: A Child of Fire",
+					Date:    time.Date(2024, time.September, 13, 20, 0, 0, 0, time.UTC).In(loc),
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "no profile",
+			filter: ViewingActivityCSVFilter{
+				Profile:     "",
+				MinDuration: 0,
+				Since:       time.Time{},
+				Until:       time.Time{},
+			},
+			want:    nil,
+			wantErr: "the file contains multiple profiles, one must be selected: Guest, Melvin",
+		},
+		{
+			name: "unknown profile",
+			filter: ViewingActivityCSVFilter{
+				Profile:     "Kids",
+				MinDuration: 0,
+				Since:       time.Time{},
+				Until:       time.Time{},
+			},
+			want:    nil,
+			wantErr: `profile "Kids" not found`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(filepath.Join("testdata", "ViewingActivity.csv"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = f.Close() })
+
+			items, err := ReadViewingActivityCSV(f, tc.filter, loc)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, items, len(tc.want))
+			for i := range tc.want {
+				assert.Equal(t, tc.want[i].Title, items[i].Title)
+				assert.Equal(t, tc.want[i].VideoID, items[i].VideoID)
+				assert.True(t, tc.want[i].Date.Equal(items[i].Date), "got %s", items[i].Date)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package netflix
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFetchViewingActivity(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "viewing_activity.json"))
+	require.NoError(t, err)
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+
+	mockCtrl := gomock.NewController(t)
+	doer := mocks.NewMockDoer(mockCtrl)
+	doer.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "/api/shakti/mre/viewingactivity", req.URL.Path)
+		assert.Equal(t, "2", req.URL.Query().Get("pg"))
+		assert.Equal(t, "100", req.URL.Query().Get("pgSize"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(data)),
+		}, nil
+	})
+
+	c := &Client{
+		HTTP: doer,
+		History: &History{
+			ItemsSearch:    make(map[string]struct{}),
+			Items:          []HistoryItem{},
+			NewActivity:    []*WatchActivity{},
+			TrackRewatches: true,
+			Locale:         "",
+			Overridden:     nil,
+			path:           "",
+			page:           nil,
+		},
+		WatchActivityURL: "",
+		ShaktiURL:        "https://www.netflix.com/api/shakti/mre",
+		Cookie:           "cookie",
+		Location:         loc,
+		DateLayout:       "",
+	}
+
+	items, err := c.FetchViewingActivity(t.Context(), 2)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	assert.Equal(t, "81482809", items[0].VideoID)
+	assert.Equal(t, `Alice in Borderland: Season 2: "Episode 8"`, items[0].Title)
+	assert.True(t, time.Date(2024, time.September, 14, 11, 12, 5, 0, loc).Equal(items[0].Date), "got %s", items[0].Date)
+	assert.Equal(t, loc, items[0].Date.Location())
+
+	assert.Equal(t, "81614419", items[1].VideoID)
+	assert.Equal(t, "Pain Hustlers", items[1].Title)
+}
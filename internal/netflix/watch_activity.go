@@ -2,20 +2,57 @@ package netflix
 
 import (
 	"fmt"
+	"time"
 )
 
 // WatchActivity contains the data from Netflix
 type WatchActivity struct {
-	Date        string
-	Title       string
-	EpisodeName string
-	IsShow      bool
-	Season      int
+	// Date is the day the media was watched on, at midnight in the
+	// timezone of the account. Zero if unknown.
+	Date time.Time `json:"date,omitzero"`
+	// VideoID is the Netflix ID of the video. Empty if unknown.
+	VideoID string `json:"videoId,omitempty"`
+	// RawTitle is the title as displayed by Netflix.
+	RawTitle string `json:"rawTitle"`
+
+	Title       string `json:"title"`
+	EpisodeName string `json:"episodeName,omitempty"`
+	IsShow      bool   `json:"isShow"`
+	Season      int    `json:"season,omitempty"`
+	// Year is the release year of the media, as given by Netflix.
+	// 0 if unknown.
+	Year int `json:"year,omitempty"`
+	// IsRewatch is true when the media was already in the history
+	// with a different viewing date.
+	IsRewatch bool `json:"isRewatch,omitempty"`
+	// Guessed is true when the title has an unusual format, and what
+	// the activity is had to be guessed.
+	Guessed bool `json:"guessed,omitempty"`
+	// Metadata contains the information Netflix has about the video.
+	// Nil if it hasn't been fetched.
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// HistoryItem returns the history item the activity comes from.
+func (h *WatchActivity) HistoryItem() HistoryItem {
+	return HistoryItem{
+		VideoID: h.VideoID,
+		Title:   h.RawTitle,
+		Date:    h.Date,
+	}
 }
 
 // String implements the Stringer interface.
 // it returns a string representation of a WatchActivity.
 func (h *WatchActivity) String() string {
+	if h.IsRewatch {
+		return h.title() + " (rewatch)"
+	}
+	return h.title()
+}
+
+// title returns the human readable title of the activity.
+func (h *WatchActivity) title() string {
 	if h.IsShow {
 		if h.Season > 0 {
 			return fmt.Sprintf("%s: season %d: %s", h.Title, h.Season, h.EpisodeName)
@@ -40,3 +77,31 @@ func (h *WatchActivity) SearchShow() string {
 	}
 	return h.Title
 }
+
+// WatchedAt returns the time to use when marking the activity as
+// watched.
+// The viewing activity page only gives us the day, so if the activity
+// happened on the same day as now we use now to keep the most accurate
+// time, otherwise we use the start of the day. If the date contains
+// a time, it is returned as is. If the date is unknown, now is returned.
+func (h *WatchActivity) WatchedAt(now time.Time) time.Time {
+	if h.Date.IsZero() {
+		return now
+	}
+	if !h.Date.Equal(startOfDay(h.Date)) {
+		return h.Date
+	}
+
+	y1, m1, d1 := h.Date.Date()
+	y2, m2, d2 := now.In(h.Date.Location()).Date()
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		return now
+	}
+	return h.Date
+}
+
+// startOfDay returns midnight of the day of t, in the location of t.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
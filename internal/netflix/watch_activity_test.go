@@ -2,8 +2,10 @@ package netflix
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWatchActivitySearchTerms(t *testing.T) {
@@ -18,11 +20,17 @@ func TestWatchActivitySearchTerms(t *testing.T) {
 		{
 			name: "movie",
 			activity: WatchActivity{
-				Date:        "",
+				Date:        time.Time{},
+				VideoID:     "",
+				RawTitle:    "",
 				Title:       "Pain Hustlers",
 				EpisodeName: "",
 				IsShow:      false,
 				Season:      0,
+				IsRewatch:   false,
+				Guessed:     false,
+				Metadata:    nil,
+				Year:        0,
 			},
 			wantQuery: "Pain Hustlers",
 			wantShow:  "",
@@ -30,11 +38,17 @@ func TestWatchActivitySearchTerms(t *testing.T) {
 		{
 			name: "episode",
 			activity: WatchActivity{
-				Date:        "",
+				Date:        time.Time{},
+				VideoID:     "",
+				RawTitle:    "",
 				Title:       "Goedam",
 				EpisodeName: "Threshold",
 				IsShow:      true,
 				Season:      0,
+				IsRewatch:   false,
+				Guessed:     false,
+				Metadata:    nil,
+				Year:        0,
 			},
 			wantQuery: "Threshold",
 			wantShow:  "Goedam",
@@ -50,3 +64,71 @@ func TestWatchActivitySearchTerms(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchActivityWatchedAt(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	now := time.Date(2024, time.September, 14, 20, 30, 0, 0, loc)
+
+	testCases := []struct {
+		name string
+		date time.Time
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "unknown date",
+			date: time.Time{},
+			now:  now,
+			want: now,
+		},
+		{
+			name: "same day",
+			date: time.Date(2024, time.September, 14, 0, 0, 0, 0, loc),
+			now:  now,
+			want: now,
+		},
+		{
+			name: "same day in a different timezone",
+			date: time.Date(2024, time.September, 14, 0, 0, 0, 0, loc),
+			now:  now.UTC(),
+			want: now,
+		},
+		{
+			name: "previous day",
+			date: time.Date(2024, time.September, 13, 0, 0, 0, 0, loc),
+			now:  now,
+			want: time.Date(2024, time.September, 13, 0, 0, 0, 0, loc),
+		},
+		{
+			name: "exact time",
+			date: time.Date(2024, time.September, 14, 18, 12, 5, 0, loc),
+			now:  now,
+			want: time.Date(2024, time.September, 14, 18, 12, 5, 0, loc),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			activity := WatchActivity{
+				Date:        tc.date,
+				VideoID:     "",
+				RawTitle:    "",
+				Title:       "Pain Hustlers",
+				EpisodeName: "",
+				IsShow:      false,
+				Season:      0,
+				IsRewatch:   false,
+				Guessed:     false,
+				Metadata:    nil,
+				Year:        0,
+			}
+			got := activity.WatchedAt(tc.now)
+			assert.True(t, tc.want.Equal(got), "got %s", got)
+		})
+	}
+}
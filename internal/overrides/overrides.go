@@ -0,0 +1,285 @@
+// Package overrides contains the user-defined mappings between
+// Netflix titles and Trakt medias, used to fix the titles that cannot
+// be parsed or matched automatically.
+package overrides
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule represents an entry of the overrides file.
+//
+// A rule matches a raw Netflix title using either Title or Pattern,
+// and either ignores the activity, or maps it to a Trakt movie, or to
+// a Trakt show.
+type Rule struct {
+	// Title is the exact title as displayed by Netflix.
+	Title string `yaml:"title,omitempty"`
+	// Pattern is a regular expression matching the title as displayed
+	// by Netflix. The named groups "season" and "episode" can be used
+	// to extract the season and the episode numbers from the title.
+	Pattern string `yaml:"pattern,omitempty"`
+
+	// Ignore means the activity should never be synced.
+	Ignore bool `yaml:"ignore,omitempty"`
+	// Movie is the Trakt ID of the movie.
+	Movie int `yaml:"movie,omitempty"`
+	// Show is the Trakt ID of the show.
+	Show int `yaml:"show,omitempty"`
+	// Season is the number of the season. 0 is for the specials.
+	// Nil if the season should be extracted from the title or is
+	// unknown.
+	Season *int `yaml:"season,omitempty"`
+	// Episode is the number of the episode in its season. 0 if the
+	// episode should be extracted from the title, or found using its
+	// name.
+	Episode int `yaml:"episode,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Override represents what an activity should be mapped to.
+type Override struct {
+	// Ignore means the activity should never be synced.
+	Ignore bool
+	// Movie is the Trakt ID of the movie. 0 if the activity is not a
+	// movie.
+	Movie int
+	// Show is the Trakt ID of the show. 0 if the activity is not an
+	// episode.
+	Show int
+	// Season is the number of the season. Nil if unknown.
+	Season *int
+	// Episode is the number of the episode in its season. 0 if
+	// unknown, in which case the episode needs to be found using its
+	// name.
+	Episode int
+}
+
+// Overrides contains the rules of an overrides file.
+// The file is only read when it changes, so Reload can be called
+// before every run to pick up the changes made by the user.
+type Overrides struct {
+	path    string
+	rules   []*Rule
+	modTime time.Time
+	size    int64
+}
+
+// New returns the overrides stored at the given path.
+// A missing file is not an error, it just means there are no overrides.
+func New(path string) (*Overrides, error) {
+	o := &Overrides{
+		path:    path,
+		rules:   []*Rule{},
+		modTime: time.Time{},
+		size:    0,
+	}
+	if err := o.Reload(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Reload reloads the rules if the file changed since the last load.
+// If the new content is invalid, the previous rules are kept, and the
+// error is only returned once, until the file changes again.
+func (o *Overrides) Reload() error {
+	info, err := os.Stat(o.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			o.rules = []*Rule{}
+			o.modTime = time.Time{}
+			o.size = 0
+			return nil
+		}
+		return fmt.Errorf("stat %s: %w", o.path, err)
+	}
+	if info.ModTime().Equal(o.modTime) && info.Size() == o.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(o.path) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil {
+		return fmt.Errorf("read %s: %w", o.path, err)
+	}
+	// The file is marked as loaded even if it's invalid, so the error
+	// isn't reported again until the user fixes it
+	o.modTime = info.ModTime()
+	o.size = info.Size()
+	rules, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", o.path, err)
+	}
+	o.rules = rules
+	return nil
+}
+
+// Add appends the rule to the overrides file, and reloads it.
+// The file is created if it doesn't exist. The rules already in the
+// file need to be valid.
+func (o *Overrides) Add(r *Rule) error {
+	if err := r.validate(); err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+	data, err := os.ReadFile(o.path) //nolint:gosec // G304: file inclusion via variable is what we want here
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read %s: %w", o.path, err)
+	}
+	if _, err = Parse(data); err != nil {
+		return fmt.Errorf("parse %s: %w", o.path, err)
+	}
+
+	// The file is edited as a YAML tree, so the comments and the
+	// formatting of the existing rules are kept
+	var doc yaml.Node
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", o.path, err)
+	}
+	if doc.Kind == 0 {
+		// The file is empty or only contains comments
+		if err = yaml.Unmarshal(append(data, "\n[]"...), &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", o.path, err)
+		}
+	}
+	rules := doc.Content[0]
+	if rules.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s doesn't contain a list of rules", o.path)
+	}
+	var rule yaml.Node
+	if err = rule.Encode(r); err != nil {
+		return fmt.Errorf("encode the rule: %w", err)
+	}
+	// The new rule is written as a block, which cannot be part of a
+	// flow sequence such as []
+	rules.Style &^= yaml.FlowStyle
+	rules.Content = append(rules.Content, &rule)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err = enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encode %s: %w", o.path, err)
+	}
+	if err = enc.Close(); err != nil {
+		return fmt.Errorf("encode %s: %w", o.path, err)
+	}
+	if err = fileutil.WriteFile(o.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("write %s: %w", o.path, err)
+	}
+	return o.Reload()
+}
+
+// Parse parses and validates the content of an overrides file.
+func Parse(data []byte) ([]*Rule, error) {
+	rules := []*Rule{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	// Typos would otherwise silently turn a rule into another one
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return rules, nil
+}
+
+// validate makes sure the rule is valid, and compiles its pattern.
+func (r *Rule) validate() error {
+	switch {
+	case r.Title == "" && r.Pattern == "":
+		return errors.New("either title or pattern is required")
+	case r.Title != "" && r.Pattern != "":
+		return errors.New("title and pattern cannot be used together")
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.re = re
+	}
+
+	targets := 0
+	for _, set := range []bool{r.Ignore, r.Movie != 0, r.Show != 0} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("exactly one of ignore, movie, or show is required")
+	}
+	if r.Show == 0 && (r.Season != nil || r.Episode != 0) {
+		return errors.New("season and episode can only be used with show")
+	}
+	if r.Season != nil && *r.Season < 0 {
+		return errors.New("season cannot be negative")
+	}
+	if r.Episode < 0 {
+		return errors.New("episode cannot be negative")
+	}
+	return nil
+}
+
+// Find returns the override of the first rule matching the given
+// Netflix title.
+func (o *Overrides) Find(title string) (*Override, bool) {
+	for _, r := range o.rules {
+		if override, ok := r.match(title); ok {
+			return override, true
+		}
+	}
+	return nil, false
+}
+
+// Has returns whether a rule matches the given Netflix title.
+func (o *Overrides) Has(title string) bool {
+	_, ok := o.Find(title)
+	return ok
+}
+
+// match returns the override of the rule if it matches the given title.
+func (r *Rule) match(title string) (*Override, bool) {
+	override := &Override{
+		Ignore:  r.Ignore,
+		Movie:   r.Movie,
+		Show:    r.Show,
+		Season:  r.Season,
+		Episode: r.Episode,
+	}
+
+	if r.re == nil {
+		return override, r.Title == title
+	}
+
+	matches := r.re.FindStringSubmatch(title)
+	if matches == nil {
+		return nil, false
+	}
+	for i, name := range r.re.SubexpNames() {
+		n, err := strconv.Atoi(matches[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case name == "season" && override.Season == nil:
+			override.Season = &n
+		case name == "episode" && override.Episode == 0:
+			override.Episode = n
+		}
+	}
+	return override, true
+}
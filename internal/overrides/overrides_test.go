@@ -0,0 +1,260 @@
+package overrides
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	rules, err := Parse([]byte(`
+- title: "Zombieverse: New Blood: \"Episode 1\""
+  ignore: true
+- pattern: '^Zombieverse: New Blood: "Episode (?P<episode>\d+)"$'
+  show: 201845
+  season: 2
+- pattern: '^Arrested Development: Season 4 Remix: Fateful Consequences: '
+  show: 1905
+  season: 0
+- title: Pain Hustlers
+  movie: 764041
+`))
+	require.NoError(t, err)
+	o := &Overrides{path: "", rules: rules, modTime: time.Time{}, size: 0}
+
+	season := func(n int) *int { return &n }
+	testCases := []struct {
+		name  string
+		title string
+		want  *Override
+	}{
+		{
+			name:  "rules are matched in order",
+			title: `Zombieverse: New Blood: "Episode 1"`,
+			want:  &Override{Ignore: true, Movie: 0, Show: 0, Season: nil, Episode: 0},
+		},
+		{
+			name:  "episode extracted from the title",
+			title: `Zombieverse: New Blood: "Episode 7"`,
+			want:  &Override{Ignore: false, Movie: 0, Show: 201845, Season: season(2), Episode: 7},
+		},
+		{
+			name:  "specials",
+			title: `Arrested Development: Season 4 Remix: Fateful Consequences: "Blockheads"`,
+			want:  &Override{Ignore: false, Movie: 0, Show: 1905, Season: season(0), Episode: 0},
+		},
+		{
+			name:  "movie",
+			title: "Pain Hustlers",
+			want:  &Override{Ignore: false, Movie: 764041, Show: 0, Season: nil, Episode: 0},
+		},
+		{
+			name:  "title must match exactly",
+			title: "Pain Hustlers 2",
+			want:  nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := o.Find(tc.title)
+			assert.Equal(t, tc.want != nil, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "no title",
+			content: "- movie: 1",
+			wantErr: "rule 0: either title or pattern is required",
+		},
+		{
+			name:    "title and pattern",
+			content: "- {title: a, pattern: a, movie: 1}",
+			wantErr: "rule 0: title and pattern cannot be used together",
+		},
+		{
+			name:    "invalid pattern",
+			content: "- {pattern: '(', movie: 1}",
+			wantErr: "rule 0: invalid pattern",
+		},
+		{
+			name:    "no target",
+			content: "- {title: a}",
+			wantErr: "rule 0: exactly one of ignore, movie, or show is required",
+		},
+		{
+			name:    "multiple targets",
+			content: "- {title: a, movie: 1, show: 2}",
+			wantErr: "rule 0: exactly one of ignore, movie, or show is required",
+		},
+		{
+			name:    "season on a movie",
+			content: "- {title: a, movie: 1, season: 2}",
+			wantErr: "rule 0: season and episode can only be used with show",
+		},
+		{
+			name:    "unknown field",
+			content: "- {title: a, shows: 1}",
+			wantErr: "field shows not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse([]byte(tc.content))
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+
+	// A missing file means no overrides
+	o, err := New(path)
+	require.NoError(t, err)
+	_, ok := o.Find("Pain Hustlers")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte("- {title: Pain Hustlers, ignore: true}"), 0o600))
+	require.NoError(t, o.Reload())
+	_, ok = o.Find("Pain Hustlers")
+	assert.True(t, ok, "the new file should have been loaded")
+
+	// An invalid file keeps the previous rules
+	require.NoError(t, os.WriteFile(path, []byte("- {title: Pain Hustlers}"), 0o600))
+	require.Error(t, o.Reload())
+	_, ok = o.Find("Pain Hustlers")
+	assert.True(t, ok, "the previous rules should have been kept")
+	require.NoError(t, o.Reload(), "an invalid file should only be reported once")
+	_, ok = o.Find("Pain Hustlers")
+	assert.True(t, ok, "the previous rules should still be used")
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, o.Reload())
+	_, ok = o.Find("Pain Hustlers")
+	assert.False(t, ok, "the rules should have been removed with the file")
+}
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	season := 1
+	rule := func() *Rule {
+		return &Rule{
+			Title:   `Dark: Staffel 1: "Geheimnisse"`,
+			Pattern: "",
+			Ignore:  false,
+			Movie:   0,
+			Show:    102867,
+			Season:  &season,
+			Episode: 1,
+			re:      nil,
+		}
+	}
+	const added = "- title: 'Dark: Staffel 1: \"Geheimnisse\"'\n  show: 102867\n  season: 1\n  episode: 1\n"
+
+	testCases := []struct {
+		desc            string
+		content         *string
+		expectedContent string
+	}{
+		{
+			desc:            "existing rules",
+			content:         ptr("# Mine\n- {title: Pain Hustlers, ignore: true} # Not a show\n"),
+			expectedContent: "# Mine\n- {title: Pain Hustlers, ignore: true} # Not a show\n" + added,
+		},
+		{
+			desc:            "flow sequence",
+			content:         ptr("[{title: Pain Hustlers, ignore: true}]"),
+			expectedContent: "- {title: Pain Hustlers, ignore: true}\n" + added,
+		},
+		{
+			desc:            "empty flow sequence",
+			content:         ptr("[]\n"),
+			expectedContent: added,
+		},
+		{
+			desc:            "comments only",
+			content:         ptr("# Mine\n"),
+			expectedContent: "# Mine\n\n" + added,
+		},
+		{
+			desc:            "empty file",
+			content:         ptr(""),
+			expectedContent: added,
+		},
+		{
+			desc:            "missing file",
+			content:         nil,
+			expectedContent: added,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "overrides.yaml")
+			if tc.content != nil {
+				require.NoError(t, os.WriteFile(path, []byte(*tc.content), 0o600))
+			}
+			o, err := New(path)
+			require.NoError(t, err)
+
+			require.NoError(t, o.Add(rule()))
+			got, ok := o.Find(`Dark: Staffel 1: "Geheimnisse"`)
+			require.True(t, ok, "the new rule should have been loaded")
+			assert.Equal(t, &Override{Ignore: false, Movie: 0, Show: 102867, Season: &season, Episode: 1}, got)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedContent, string(data))
+		})
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	o, err := New(path)
+	require.NoError(t, err)
+
+	err = o.Add(&Rule{Title: "", Pattern: "", Ignore: true, Movie: 0, Show: 0, Season: nil, Episode: 0, re: nil})
+	require.Error(t, err, "invalid rules should be rejected")
+
+	// The file is only written if its rules are valid, so the user
+	// doesn't lose them
+	invalid := "- {title: Pain Hustlers}\n"
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	err = o.Add(&Rule{Title: "Dark", Pattern: "", Ignore: true, Movie: 0, Show: 0, Season: nil, Episode: 0, re: nil})
+	require.Error(t, err, "an invalid file should not be modified")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, invalid, string(data))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
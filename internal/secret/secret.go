@@ -37,6 +37,12 @@ func (s Secret) Get() string {
 	return *s.secret
 }
 
+// IsEmpty returns whether the secret is not set, or is an empty
+// string
+func (s Secret) IsEmpty() bool {
+	return s.secret == nil || *s.secret == ""
+}
+
 // EnvDecode implements the envconfig.Decoder interface
 func (s *Secret) EnvDecode(val string) error {
 	s.secret = &val
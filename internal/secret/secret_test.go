@@ -38,6 +38,14 @@ func TestNewSecret(t *testing.T) {
 		assert.Equal(t, mySecret, s.Get())
 	})
 
+	t.Run("IsEmpty() should only be true for unset or empty secrets", func(t *testing.T) {
+		t.Parallel()
+
+		assert.False(t, secret.NewSecret(mySecret).IsEmpty())
+		assert.True(t, secret.NewSecret("").IsEmpty())
+		assert.True(t, secret.Secret{}.IsEmpty())
+	})
+
 	t.Run("Stringer should return redacted data", func(t *testing.T) {
 		t.Parallel()
 
@@ -12,10 +12,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Nivl/trakt-netflix/internal/errutil"
+	"github.com/Nivl/trakt-netflix/internal/fileutil"
 	"github.com/Nivl/trakt-netflix/internal/pathutil"
 	"github.com/Nivl/trakt-netflix/internal/secret"
 )
@@ -26,8 +28,14 @@ const (
 	traktHTTPTimeout            = 30 * time.Second
 	traktTransientRetryAttempts = 3
 	traktTransientRetryDelay    = 250 * time.Millisecond
+	traktRateLimitRetryAttempts = 5
+	traktRateLimitDefaultDelay  = time.Second
 )
 
+// ErrDryRun is returned when trying to change data on Trakt while the
+// client is in dry-run mode.
+var ErrDryRun = errors.New("not allowed in dry-run mode")
+
 // ErrPendingAuthorization is returned when the authorization is still
 // pending, waiting for the user to complete the authorization flow.
 var ErrPendingAuthorization = errors.New("pending authorization")
@@ -47,7 +55,8 @@ type AccessTokenInfo struct {
 type Client struct {
 	// http is the HTTP client used to make requests to the Trakt API.
 	http *http.Client
-	// retrySleep waits between bounded retry attempts.
+	// retrySleep waits between bounded retry attempts. If nil, the
+	// wait is interrupted when the context of the request is done.
 	retrySleep func(time.Duration)
 	// baseURL is the base URL for the Trakt API.
 	baseURL string
@@ -60,6 +69,9 @@ type Client struct {
 
 	auth         AccessTokenInfo
 	authFilePath string
+	// dryRun prevents the client from changing anything on Trakt or
+	// on disk.
+	dryRun bool
 }
 
 // ClientConfig holds the configuration for the Trakt client.
@@ -68,43 +80,61 @@ type ClientConfig struct {
 	ClientID        string        `env:"CLIENT_ID,required"`
 	RedirectURI     string        `env:"REDIRECT_URI,required"`
 	RelAuthFilePath string        `env:"AUTH_FILE_REL_PATH"`
+	// DryRun prevents the client from marking anything as watched, and
+	// from writing the auth file.
+	DryRun bool
+	// BaseURL is the URL of the Trakt API. Defaults to
+	// https://api.trakt.tv.
+	BaseURL string
 }
 
 // NewClient creates a new Trakt API client with the provided configuration.
 // It reads the authentication tokens from the specified file path.
-func NewClient(cfg ClientConfig) (clt *Client, err error) {
+func NewClient(cfg ClientConfig) (*Client, error) {
 	if cfg.RelAuthFilePath == "" {
 		cfg.RelAuthFilePath = "trakt_auth.json"
 	}
-
-	authFilePath := filepath.Join(pathutil.ConfigDir(), cfg.RelAuthFilePath)
-
-	// TODO(melvin): Use something more secure than ReadFile, to avoid
-	// loading a huge file in memory.
-	f, err := os.Open(authFilePath) //nolint:gosec // G304: file inclusion via variable is what we want here
-	if err != nil && !os.IsNotExist(err) {
-		return nil, fmt.Errorf("open auth file: %w", err)
-	}
-	var authTokens AccessTokenInfo
-	if !os.IsNotExist(err) {
-		defer errutil.RunAndSetError(f.Close, &err, "close auth file")
-		if err := json.NewDecoder(f).Decode(&authTokens); err != nil {
-			return nil, fmt.Errorf("decode auth file: %w", err)
-		}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.trakt.tv"
 	}
 
-	return &Client{
+	c := &Client{
 		http: &http.Client{
 			Timeout: traktHTTPTimeout,
 		},
-		retrySleep:   time.Sleep,
-		baseURL:      "https://api.trakt.tv",
+		retrySleep:   nil,
+		baseURL:      cfg.BaseURL,
 		clientID:     cfg.ClientID,
 		clientSecret: cfg.ClientSecret,
 		redirectURI:  cfg.RedirectURI,
 		authFilePath: filepath.Join(pathutil.ConfigDir(), cfg.RelAuthFilePath),
-		auth:         authTokens,
-	}, nil
+		auth:         AccessTokenInfo{},
+		dryRun:       cfg.DryRun,
+	}
+	// The recovery of a file is reported when the files get reloaded
+	// before being used
+	if err := c.LoadAuthFile(); err != nil && !errors.Is(err, fileutil.ErrRecovered) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadAuthFile loads the authentication tokens from the auth file on
+// disk. The current tokens are kept if the file doesn't exist.
+// The backup of the auth file is used if the file is corrupted, in
+// which case an error wrapping fileutil.ErrRecovered is returned.
+func (c *Client) LoadAuthFile() error {
+	authTokens := c.auth
+	err := fileutil.ReadJSON(c.authFilePath, &authTokens)
+	if err != nil {
+		err = fmt.Errorf("load auth file: %w", err)
+		// The backup got loaded, the error only needs to be reported
+		if !errors.Is(err, fileutil.ErrRecovered) {
+			return err
+		}
+	}
+	c.auth = authTokens
+	return err
 }
 
 // requestOptions holds options for the request
@@ -141,7 +171,8 @@ func (c *Client) request(ctx context.Context, method, path string, body json.Raw
 		o(&options)
 	}
 
-	for attempt := range traktTransientRetryAttempts {
+	rateLimited := 0
+	for attempt := 0; attempt < traktTransientRetryAttempts; attempt++ {
 		var bodyBuffer io.Reader = http.NoBody
 		if body != nil {
 			bodyBuffer = bytes.NewReader(body)
@@ -150,11 +181,24 @@ func (c *Client) request(ctx context.Context, method, path string, body json.Raw
 		resp, respBody, err = c._request(ctx, method, path, bodyBuffer, options)
 		if err != nil {
 			if resp == nil && shouldRetryTransientRequest(ctx, err, attempt) {
-				c.sleepBeforeRetry(traktRetryDelay(attempt))
+				if err = c.sleepBeforeRetry(ctx, traktRetryDelay(attempt)); err != nil {
+					return nil, nil, err
+				}
 				continue
 			}
 			return resp, respBody, err
 		}
+		// Trakt tells us how long to wait when we hit the rate limit.
+		// This doesn't count as a failed attempt.
+		// https://trakt.docs.apiary.io/#introduction/rate-limiting
+		if resp.StatusCode == http.StatusTooManyRequests && rateLimited < traktRateLimitRetryAttempts {
+			rateLimited++
+			attempt--
+			if err = c.sleepBeforeRetry(ctx, retryAfter(resp.Header)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
 		if !options.noAuth && !options.dontRetryOnAuthFailure && resp.StatusCode == http.StatusUnauthorized {
 			_, err := c.RefreshToken(ctx, c.auth.RefreshToken.Get())
 			if err != nil {
@@ -213,16 +257,37 @@ func shouldRetryTransientRequest(ctx context.Context, err error, attempt int) bo
 	return errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err)
 }
 
+// retryAfter returns how long to wait before retrying a rate-limited
+// request, based on the Retry-After header.
+func retryAfter(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return traktRateLimitDefaultDelay
+	}
+	return time.Duration(secs) * time.Second
+}
+
 func traktRetryDelay(attempt int) time.Duration {
 	return time.Duration(attempt+1) * traktTransientRetryDelay
 }
 
-func (c *Client) sleepBeforeRetry(delay time.Duration) {
+// sleepBeforeRetry waits for delay before retrying a request.
+// An error is returned if ctx is done before the end of the wait.
+func (c *Client) sleepBeforeRetry(ctx context.Context, delay time.Duration) error {
 	if c.retrySleep != nil {
 		c.retrySleep(delay)
-		return
+	} else {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
 	}
-	time.Sleep(delay)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("wait before retrying: %w", err)
+	}
+	return nil
 }
 
 func (c *Client) post(ctx context.Context, path string, body any, opts ...requestOptionsFunc) (resp *http.Response, respBody []byte, err error) {
@@ -382,6 +447,7 @@ func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*Refres
 type SearchResponse struct {
 	Results []struct {
 		Type    SearchTypes `json:"type"`
+		Score   float64     `json:"score"`
 		Movie   Media       `json:"movie"`
 		Episode Episode     `json:"episode"`
 		Show    Media       `json:"show"`
@@ -393,12 +459,21 @@ type SearchRequest struct {
 	Type  SearchTypes
 	Query string
 	Show  string
+	// Fields restricts the search to the provided fields (ex. "title",
+	// "aliases", "translations"). All the fields are searched if
+	// empty.
+	Fields []string
 }
 
 // Search searches for a media item on Trakt using the provided query parameters.
+// The results contain the extended info of the medias.
 func (c *Client) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
 	query := url.Values{}
 	query.Set("query", req.Query)
+	query.Set("extended", "full")
+	if len(req.Fields) > 0 {
+		query.Set("fields", strings.Join(req.Fields, ","))
+	}
 	searchURL := "/search/" + string(req.Type) + "?" + query.Encode()
 
 	resp, body, err := c.get(ctx, searchURL, withNoAuth()) //nolint:bodyclose // the body is closed in _request
@@ -418,6 +493,46 @@ func (c *Client) Search(ctx context.Context, req SearchRequest) (*SearchResponse
 	return &searchResponse, nil
 }
 
+// GetMovieAliases returns the titles a movie is known under, in every
+// country.
+func (c *Client) GetMovieAliases(ctx context.Context, movieID string) ([]Alias, error) {
+	resp, body, err := c.get(ctx, "/movies/"+url.PathEscape(movieID)+"/aliases", withNoAuth()) //nolint:bodyclose // the body is closed in _request
+	if err != nil {
+		return nil, fmt.Errorf("get movie aliases: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http %d. See %s", resp.StatusCode, traktErrorCodeURL)
+	}
+
+	var aliases []Alias
+	if err = json.Unmarshal(body, &aliases); err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// GetShowTranslations returns the translations of a show, in every
+// language.
+func (c *Client) GetShowTranslations(ctx context.Context, showID string) ([]Translation, error) {
+	resp, body, err := c.get(ctx, "/shows/"+url.PathEscape(showID)+"/translations", withNoAuth()) //nolint:bodyclose // the body is closed in _request
+	if err != nil {
+		return nil, fmt.Errorf("get show translations: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http %d. See %s", resp.StatusCode, traktErrorCodeURL)
+	}
+
+	var translations []Translation
+	if err = json.Unmarshal(body, &translations); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
 // GetShowSeasons returns the seasons for a show and can optionally include all episodes.
 func (c *Client) GetShowSeasons(ctx context.Context, showID string, withEpisodes bool) ([]Season, error) {
 	query := url.Values{}
@@ -492,6 +607,10 @@ type MarkAsWatchedResponse struct {
 
 // MarkAsWatched marks a media item as watched on Trakt.
 func (c *Client) MarkAsWatched(ctx context.Context, req *MarkAsWatchedRequest) (*MarkAsWatchedResponse, error) {
+	if c.dryRun {
+		return nil, fmt.Errorf("mark as watched: %w", ErrDryRun)
+	}
+
 	resp, body, err := c.post(ctx, "/sync/history", req) //nolint:bodyclose // the body is closed in _request
 	if err != nil {
 		return nil, fmt.Errorf("mark as watched: %w", err)
@@ -520,8 +639,14 @@ type unsecuredAccessTokenInfo struct {
 }
 
 // WriteAuthFile writes the current authentication data to the
-// auth file on disk.
+// auth file on disk. The previous version of the file is kept as a
+// backup.
+// Noop in dry-run mode.
 func (c *Client) WriteAuthFile() error {
+	if c.dryRun {
+		return nil
+	}
+
 	auth := unsecuredAccessTokenInfo{
 		AccessTokenInfo: c.auth,
 		AccessToken:     c.auth.AccessToken.Get(),
@@ -531,7 +656,7 @@ func (c *Client) WriteAuthFile() error {
 	if err != nil {
 		return fmt.Errorf("marshal auth data: %w", err)
 	}
-	return os.WriteFile(c.authFilePath, data, 0o600)
+	return fileutil.WriteFile(c.authFilePath, data)
 }
 
 // IsAuthenticated checks if the client is authenticated.
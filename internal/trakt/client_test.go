@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -34,26 +35,48 @@ func TestSearch(t *testing.T) {
 		{
 			name: "movie search",
 			req: SearchRequest{
-				Type:  SearchTypeMovie,
-				Query: "Pain Hustlers",
-				Show:  "",
+				Type:   SearchTypeMovie,
+				Query:  "Pain Hustlers",
+				Show:   "",
+				Fields: nil,
 			},
 			wantPath: "/search/movie",
 			wantQuery: map[string]string{
-				"query": "Pain Hustlers",
+				"query":    "Pain Hustlers",
+				"extended": "full",
+				"fields":   "",
 			},
 			wantShowPresent: false,
 		},
 		{
 			name: "episode search with show",
 			req: SearchRequest{
-				Type:  SearchTypeEpisode,
-				Query: "Threshold",
-				Show:  "Goedam",
+				Type:   SearchTypeEpisode,
+				Query:  "Threshold",
+				Show:   "Goedam",
+				Fields: nil,
 			},
 			wantPath: "/search/episode",
 			wantQuery: map[string]string{
-				"query": "Threshold",
+				"query":    "Threshold",
+				"extended": "full",
+				"fields":   "",
+			},
+			wantShowPresent: false,
+		},
+		{
+			name: "show search using the aliases",
+			req: SearchRequest{
+				Type:   SearchTypeShow,
+				Query:  "Extraordinary Attorney Woo",
+				Show:   "",
+				Fields: []string{"aliases", "translations"},
+			},
+			wantPath: "/search/show",
+			wantQuery: map[string]string{
+				"query":    "Extraordinary Attorney Woo",
+				"extended": "full",
+				"fields":   "aliases,translations",
 			},
 			wantShowPresent: false,
 		},
@@ -70,7 +93,9 @@ func TestSearch(t *testing.T) {
 				gotPath = r.URL.Path
 				q := r.URL.Query()
 				gotQuery = map[string]string{
-					"query": q.Get("query"),
+					"query":    q.Get("query"),
+					"extended": q.Get("extended"),
+					"fields":   q.Get("fields"),
 				}
 				gotShowPresent = q.Has("show")
 
@@ -177,6 +202,54 @@ func TestGetSeasonEpisodes(t *testing.T) {
 	assert.Equal(t, "Episode 1", episodes[0].Title)
 }
 
+func TestGetMovieAliases(t *testing.T) {
+	t.Parallel()
+
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[{"title":"Parasite","country":"us"},{"title":"기생충","country":"kr"}]`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := new(Client)
+	client.http = srv.Client()
+	client.baseURL = srv.URL
+	client.clientID = "test-client-id"
+	client.clientSecret = secret.NewSecret("")
+
+	aliases, err := client.GetMovieAliases(t.Context(), "parasite-2019")
+	require.NoError(t, err)
+	assert.Equal(t, "/movies/parasite-2019/aliases", gotPath)
+	assert.Equal(t, []Alias{{Title: "Parasite", Country: "us"}, {Title: "기생충", Country: "kr"}}, aliases)
+}
+
+func TestGetShowTranslations(t *testing.T) {
+	t.Parallel()
+
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[{"title":"이상한 변호사 우영우","overview":"","language":"ko","country":"kr"}]`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := new(Client)
+	client.http = srv.Client()
+	client.baseURL = srv.URL
+	client.clientID = "test-client-id"
+	client.clientSecret = secret.NewSecret("")
+
+	translations, err := client.GetShowTranslations(t.Context(), "extraordinary-attorney-woo")
+	require.NoError(t, err)
+	assert.Equal(t, "/shows/extraordinary-attorney-woo/translations", gotPath)
+	assert.Equal(t, []Translation{{Title: "이상한 변호사 우영우", Language: "ko", Country: "kr"}}, translations)
+}
+
 func TestSearchRetriesTransientTransportTimeouts(t *testing.T) {
 	t.Parallel()
 
@@ -207,9 +280,10 @@ func TestSearchRetriesTransientTransportTimeouts(t *testing.T) {
 	client.retrySleep = func(time.Duration) {}
 
 	searchResponse, err := client.Search(t.Context(), SearchRequest{
-		Type:  SearchTypeMovie,
-		Query: "Pain Hustlers",
-		Show:  "",
+		Type:   SearchTypeMovie,
+		Query:  "Pain Hustlers",
+		Show:   "",
+		Fields: nil,
 	})
 	require.NoError(t, err)
 	require.NotNil(t, searchResponse)
@@ -280,12 +354,115 @@ func TestSearchDoesNotRetryWhenServerResponds(t *testing.T) {
 	client.retrySleep = func(time.Duration) {}
 
 	searchResponse, err := client.Search(t.Context(), SearchRequest{
-		Type:  SearchTypeMovie,
-		Query: "Pain Hustlers",
-		Show:  "",
+		Type:   SearchTypeMovie,
+		Query:  "Pain Hustlers",
+		Show:   "",
+		Fields: nil,
 	})
 	require.Nil(t, searchResponse)
 	require.Error(t, err)
 	assert.Equal(t, 1, attempts)
 	assert.ErrorContains(t, err, "http 504")
 }
+
+func TestSearchWaitsWhenRateLimited(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	var sleeps []time.Duration
+	client := new(Client)
+	client.http = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts <= traktTransientRetryAttempts {
+				header := make(http.Header)
+				header.Set("Retry-After", "2")
+				return &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     header,
+					Body:       io.NopCloser(strings.NewReader(`{}`)),
+					Request:    req,
+				}, nil
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(strings.NewReader("[]")),
+				Request:    req,
+			}, nil
+		}),
+	}
+	client.baseURL = "https://example.test"
+	client.clientID = "test-client-id"
+	client.clientSecret = secret.NewSecret("")
+	client.retrySleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+
+	searchResponse, err := client.Search(t.Context(), SearchRequest{
+		Type:   SearchTypeMovie,
+		Query:  "Pain Hustlers",
+		Show:   "",
+		Fields: nil,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, searchResponse)
+	assert.Equal(t, traktTransientRetryAttempts+1, attempts, "rate limits should not count as failed attempts")
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second, 2 * time.Second}, sleeps)
+}
+
+func TestRateLimitWaitStopsWithContext(t *testing.T) {
+	t.Parallel()
+
+	client := new(Client)
+	client.http = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			header := make(http.Header)
+			header.Set("Retry-After", "3600")
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(`{}`)),
+				Request:    req,
+			}, nil
+		}),
+	}
+	client.baseURL = "https://example.test"
+	client.clientID = "test-client-id"
+	client.clientSecret = secret.NewSecret("")
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Search(ctx, SearchRequest{
+		Type:   SearchTypeMovie,
+		Query:  "Pain Hustlers",
+		Show:   "",
+		Fields: nil,
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded, "the wait should stop once the context is done")
+}
+
+func TestDryRunDoesNotChangeAnything(t *testing.T) {
+	t.Parallel()
+
+	client := new(Client)
+	client.http = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			t.Errorf("unexpected request to %s", req.URL)
+			return nil, context.Canceled
+		}),
+	}
+	client.baseURL = "https://example.test"
+	client.authFilePath = filepath.Join(t.TempDir(), "trakt_auth.json")
+	client.dryRun = true
+
+	_, err := client.MarkAsWatched(t.Context(), &MarkAsWatchedRequest{
+		Movies:   []MarkAsWatched{{WatchedAt: "", IDs: IDs{Trakt: 1, Slug: nil, IMDB: nil, TMDB: nil, TVDB: nil}}},
+		Episodes: []MarkAsWatched{},
+	})
+	require.ErrorIs(t, err, ErrDryRun)
+
+	require.NoError(t, client.WriteAuthFile())
+	assert.NoFileExists(t, client.authFilePath)
+}
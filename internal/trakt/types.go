@@ -28,6 +28,31 @@ type Media struct {
 	Title string `json:"title"`
 	Year  int    `json:"year"`
 	IDs   IDs    `json:"ids"`
+
+	// The following fields are only returned with the extended info.
+
+	// Votes is the number of votes the media got on Trakt.
+	Votes int `json:"votes,omitempty"`
+	// Homepage is the URL of the official website of the media.
+	Homepage string `json:"homepage,omitempty"`
+	// Network is the network that airs the show. Always empty for
+	// movies.
+	Network string `json:"network,omitempty"`
+}
+
+// Alias represents another title of a media, used in a given
+// country.
+type Alias struct {
+	Title   string `json:"title"`
+	Country string `json:"country"`
+}
+
+// Translation represents the translation of a media in a given
+// language.
+type Translation struct {
+	Title    string `json:"title"`
+	Language string `json:"language"`
+	Country  string `json:"country"`
 }
 
 // Episode represents a TV episode in the Trakt API.
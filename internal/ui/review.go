@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+)
+
+// Review prompts the user to pick the match of each entry of the
+// review queue, and returns their decisions.
+// The user can pick a candidate, save it as an override, ignore the
+// activity forever, skip it, or stop reviewing.
+func Review(ctx context.Context, in io.Reader, out io.Writer, entries []*activitytracker.ReviewEntry) ([]activitytracker.ReviewDecision, error) {
+	decisions := []activitytracker.ReviewDecision{}
+	scanner := bufio.NewScanner(in)
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		h := entry.Activity
+		fmt.Fprintf(out, "\n[%d/%d] %s\n", i+1, len(entries), h.RawTitle)
+		if !h.Date.IsZero() {
+			fmt.Fprintf(out, "Watched on %s\n", h.Date.Format("2006-01-02"))
+		}
+		fmt.Fprintf(out, "Reason: %s\n", entry.Reason)
+		for j, m := range entry.Candidates {
+			fmt.Fprintf(out, "  %d) %s\n", j+1, describeMatch(m))
+		}
+
+		for {
+			fmt.Fprintf(out, "Pick a candidate (1-%d), add \"o\" to also save it as an override (ex. 1o), \"i\" to ignore it forever, \"s\" to skip, or \"q\" to quit: ", len(entry.Candidates))
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, fmt.Errorf("read answer: %w", err)
+				}
+				return decisions, nil
+			}
+
+			answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			switch answer {
+			case "q":
+				return decisions, nil
+			case "s", "":
+			case "i":
+				decisions = append(decisions, activitytracker.ReviewDecision{
+					Entry:    entry,
+					Match:    nil,
+					Override: true,
+				})
+			default:
+				override := strings.HasSuffix(answer, "o")
+				n, err := strconv.Atoi(strings.TrimSuffix(answer, "o"))
+				if err != nil || n < 1 || n > len(entry.Candidates) {
+					fmt.Fprintf(out, "Invalid answer %q\n", answer)
+					continue
+				}
+				decisions = append(decisions, activitytracker.ReviewDecision{
+					Entry:    entry,
+					Match:    entry.Candidates[n-1],
+					Override: override,
+				})
+			}
+			break
+		}
+	}
+	return decisions, nil
+}
+
+// describeMatch returns a human readable description of a candidate.
+// Ex: "pain-hustlers-2023 (2023)" or "alice-in-borderland S02E08 "Episode 8""
+func describeMatch(m *activitytracker.Match) string {
+	desc := m.String()
+	switch {
+	case m.Episode != nil:
+		desc += fmt.Sprintf(" %q", m.Episode.Title)
+	case m.Movie.Year > 0:
+		desc += fmt.Sprintf(" (%d)", m.Movie.Year)
+	}
+	if m.Score < 1 {
+		desc += fmt.Sprintf(" - score %.2f", m.Score)
+	}
+	return desc
+}
@@ -0,0 +1,191 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/app"
+)
+
+// syncResponse is the response of POST /sync.
+type syncResponse struct {
+	Profiles []profileSync `json:"profiles"`
+}
+
+// profileSync contains the outcome of the sync of a profile.
+type profileSync struct {
+	Profile string `json:"profile"`
+	syncOutcome
+}
+
+// syncOutcome contains the outcome of a sync.
+type syncOutcome struct {
+	// Added is the number of activities added to Trakt.
+	Added int `json:"added"`
+	// Skipped is the number of activities that couldn't be matched, and
+	// that will be retried or reviewed.
+	Skipped int `json:"skipped"`
+	// Failed is the number of activities that got matched, but that
+	// Trakt didn't add.
+	Failed int                           `json:"failed"`
+	Items  []activitytracker.SummaryItem `json:"items"`
+	// Error is set if the sync didn't run, or failed before syncing
+	// anything.
+	Error string `json:"error,omitempty"`
+}
+
+// newSyncOutcome returns the outcome of a sync.
+func newSyncOutcome(summary *activitytracker.Summary, err error) syncOutcome {
+	res := syncOutcome{
+		Added:   0,
+		Skipped: 0,
+		Failed:  0,
+		Items:   []activitytracker.SummaryItem{},
+		Error:   "",
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if summary == nil {
+		return res
+	}
+
+	res.Items = summary.Items
+	for _, item := range summary.Items {
+		switch item.Status {
+		case activitytracker.ItemStatusAdded:
+			res.Added++
+		case activitytracker.ItemStatusUnmatched,
+			activitytracker.ItemStatusLowConfidence,
+			activitytracker.ItemStatusNeedsReview:
+			res.Skipped++
+		case activitytracker.ItemStatusNotFound,
+			activitytracker.ItemStatusFailed:
+			res.Failed++
+		}
+	}
+	return res
+}
+
+// statusResponse is the response of GET /status.
+type statusResponse struct {
+	Profiles []profileStatus `json:"profiles"`
+}
+
+// profileStatus contains the state of a profile.
+type profileStatus struct {
+	Profile string `json:"profile"`
+	Syncing bool   `json:"syncing"`
+	// LastRun is nil if the profile hasn't been synced since the
+	// service started.
+	LastRun *runStatus `json:"lastRun,omitempty"`
+	// Pending and ToReview are the number of activities waiting to be
+	// retried or reviewed. They are nil if the profile is busy.
+	Pending  *int `json:"pending,omitempty"`
+	ToReview *int `json:"toReview,omitempty"`
+}
+
+// runStatus contains the outcome of a run.
+type runStatus struct {
+	StartedAt time.Time `json:"startedAt"`
+	// Duration is the duration of the run, in seconds.
+	Duration float64 `json:"duration"`
+	syncOutcome
+}
+
+// newProfileStatus returns the current state of the profile.
+func newProfileStatus(t *app.Tracker) profileStatus {
+	status := profileStatus{
+		Profile:  t.Profile.Name,
+		Syncing:  t.Syncing(),
+		LastRun:  nil,
+		Pending:  nil,
+		ToReview: nil,
+	}
+	if runs := t.Runs(); len(runs) > 0 {
+		run := runs[0]
+		var err error
+		if run.Err != "" {
+			err = errors.New(run.Err)
+		}
+		status.LastRun = &runStatus{
+			StartedAt:   run.StartedAt,
+			Duration:    run.Duration.Seconds(),
+			syncOutcome: newSyncOutcome(run.Summary, err),
+		}
+	}
+	t.TryDo(func() {
+		pending, toReview := len(t.Activity.Pending()), len(t.Activity.Reviews())
+		status.Pending, status.ToReview = &pending, &toReview
+	})
+	return status
+}
+
+// healthz reports that the service is up.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// status returns the state of every profile.
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	res := statusResponse{
+		Profiles: make([]profileStatus, 0, len(s.trackers)),
+	}
+	for _, t := range s.trackers {
+		res.Profiles = append(res.Profiles, newProfileStatus(t))
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// syncNow syncs the provided profiles, and returns the outcome of
+// their sync.
+// The response is a 409 if a profile was already being synced, a 503
+// if the service is stopping, and a 500 if a sync failed.
+func (s *Server) syncNow(w http.ResponseWriter, r *http.Request, trackers []*app.Tracker) {
+	res := syncResponse{
+		Profiles: make([]profileSync, 0, len(trackers)),
+	}
+	code := http.StatusOK
+	for _, t := range trackers {
+		// The sync is not tied to the request, so a client that
+		// disconnects doesn't interrupt it
+		summary, err := s.sync(s.ctx, t)
+		switch {
+		case errors.Is(err, app.ErrSyncInProgress):
+			if code == http.StatusOK {
+				code = http.StatusConflict
+			}
+		case errors.Is(err, app.ErrClosed):
+			if code != http.StatusInternalServerError {
+				code = http.StatusServiceUnavailable
+			}
+		case err != nil:
+			code = http.StatusInternalServerError
+		}
+		res.Profiles = append(res.Profiles, profileSync{
+			Profile:     t.Profile.Name,
+			syncOutcome: newSyncOutcome(summary, err),
+		})
+	}
+	writeJSON(w, r, code, res)
+}
+
+// acceptsHTML returns whether the request comes from a browser, in
+// which case it expects a page instead of JSON.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// writeJSON sends v as JSON.
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "failed to send the response", "path", r.URL.Path, "error", err.Error())
+	}
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSyncOutcome(t *testing.T) {
+	t.Parallel()
+
+	t.Run("counts the items by outcome", func(t *testing.T) {
+		t.Parallel()
+
+		summary := &activitytracker.Summary{
+			Items: []activitytracker.SummaryItem{
+				{Activity: "a", Status: activitytracker.ItemStatusAdded, Error: "", Score: 1},
+				{Activity: "b", Status: activitytracker.ItemStatusAdded, Error: "", Score: 0.95},
+				{Activity: "c", Status: activitytracker.ItemStatusUnmatched, Error: "no match", Score: 0},
+				{Activity: "d", Status: activitytracker.ItemStatusLowConfidence, Error: "low confidence", Score: 0.8},
+				{Activity: "e", Status: activitytracker.ItemStatusNeedsReview, Error: "ambiguous", Score: 0},
+				{Activity: "f", Status: activitytracker.ItemStatusNotFound, Error: "", Score: 1},
+				{Activity: "g", Status: activitytracker.ItemStatusFailed, Error: "500", Score: 1},
+			},
+		}
+		res := newSyncOutcome(summary, nil)
+		assert.Equal(t, 2, res.Added)
+		assert.Equal(t, 3, res.Skipped)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, summary.Items, res.Items)
+		assert.Empty(t, res.Error)
+	})
+
+	t.Run("reports the error of a sync that didn't run", func(t *testing.T) {
+		t.Parallel()
+
+		res := newSyncOutcome(nil, errors.New("a sync is already in progress"))
+		assert.Equal(t, "a sync is already in progress", res.Error)
+		assert.Zero(t, res.Added+res.Skipped+res.Failed)
+		assert.NotNil(t, res.Items, "items should be an empty list in JSON")
+	})
+}
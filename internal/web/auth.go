@@ -0,0 +1,94 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// tokenCookie is the name of the cookie containing the token.
+const tokenCookie = "token"
+
+// authenticate only lets through the requests authenticated with the
+// basic authentication or with the token.
+// Opening a page with ?token=<token> stores the token in a cookie, so
+// the token only needs to be given once.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get(tokenCookie); token != "" && s.validToken(token) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     tokenCookie,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+				Secure:   r.TLS != nil,
+			})
+			// We don't want the token to stay in the URL
+			query := r.URL.Query()
+			query.Del(tokenCookie)
+			u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+			http.Redirect(w, r, u.String(), http.StatusSeeOther)
+			return
+		}
+
+		if s.authenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.cfg.Password.IsEmpty() {
+			w.Header().Set("WWW-Authenticate", `Basic realm="trakt-netflix", charset="UTF-8"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// authenticated checks if the request contains valid credentials.
+func (s *Server) authenticated(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.validToken(token)
+	}
+	if cookie, err := r.Cookie(tokenCookie); err == nil && s.validToken(cookie.Value) {
+		return true
+	}
+	if username, password, ok := r.BasicAuth(); ok && !s.cfg.Password.IsEmpty() {
+		validUsername := subtle.ConstantTimeCompare([]byte(username), []byte(s.cfg.Username)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(s.cfg.Password.Get())) == 1
+		return validUsername && validPassword
+	}
+	return false
+}
+
+// validToken checks if the provided token is the one of the config.
+func (s *Server) validToken(token string) bool {
+	return !s.cfg.Token.IsEmpty() && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token.Get())) == 1
+}
+
+// sameOrigin rejects the requests that change something, and that
+// come from another website. Browsers send the credentials with the
+// requests of any website, so without this check any website could
+// make the user trigger actions.
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Header.Get("Sec-Fetch-Site") {
+		case "", "same-origin", "none":
+		default:
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,240 @@
+package web
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/app"
+	"github.com/Nivl/trakt-netflix/internal/netflix"
+)
+
+// maxHistoryItems is the number of items of the history displayed.
+const maxHistoryItems = 100
+
+// indexPage contains the data of the index page.
+type indexPage struct {
+	// Message is the outcome of the last action of the user.
+	Message  string
+	Profiles []*profileView
+}
+
+// profileView contains the state of a profile.
+// The data is copied from the tracker, so the page can be rendered
+// while the profile gets synced.
+type profileView struct {
+	Name string
+	// Busy is true when the profile was being synced, in which case
+	// only the runs are set.
+	Busy    bool
+	Runs    []app.Run
+	Pending []activitytracker.QueueEntry
+	Reviews []activitytracker.ReviewEntry
+	History []netflix.HistoryItem
+}
+
+// index renders the state of every profile.
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	page := indexPage{
+		Message:  r.URL.Query().Get("msg"),
+		Profiles: make([]*profileView, 0, len(s.trackers)),
+	}
+	for _, t := range s.trackers {
+		page.Profiles = append(page.Profiles, newProfileView(t))
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "index.html", page); err != nil {
+		slog.ErrorContext(r.Context(), "failed to render the web UI", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		slog.ErrorContext(r.Context(), "failed to send the web UI", "error", err.Error())
+	}
+}
+
+// newProfileView returns the current state of the profile.
+func newProfileView(t *app.Tracker) *profileView {
+	v := &profileView{
+		Name:    t.Profile.Name,
+		Busy:    true,
+		Runs:    t.Runs(),
+		Pending: []activitytracker.QueueEntry{},
+		Reviews: []activitytracker.ReviewEntry{},
+		History: []netflix.HistoryItem{},
+	}
+	t.TryDo(func() {
+		v.Busy = false
+		for _, e := range t.Activity.Pending() {
+			entry := *e
+			activity := *e.Activity
+			entry.Activity = &activity
+			v.Pending = append(v.Pending, entry)
+		}
+		for _, e := range t.Activity.Reviews() {
+			entry := *e
+			activity := *e.Activity
+			entry.Activity = &activity
+			v.Reviews = append(v.Reviews, entry)
+		}
+		v.History = slices.Clone(t.Netflix.History.Items)
+	})
+
+	slices.SortStableFunc(v.History, func(a, b netflix.HistoryItem) int {
+		return b.Date.Compare(a.Date)
+	})
+	v.History = v.History[:min(len(v.History), maxHistoryItems)]
+	slices.SortStableFunc(v.Pending, func(a, b activitytracker.QueueEntry) int {
+		return cmp.Compare(a.NextRetry.Unix(), b.NextRetry.Unix())
+	})
+	return v
+}
+
+// forceSync syncs the selected profile, or all the profiles if none is
+// selected.
+// Browsers are sent back to the index while the sync runs in the
+// background, the other clients get the outcome of the sync as JSON.
+func (s *Server) forceSync(w http.ResponseWriter, r *http.Request) {
+	trackers := s.trackers
+	if r.FormValue("profile") != "" {
+		t, ok := s.tracker(r.FormValue("profile"))
+		if !ok {
+			http.Error(w, "profile not found", http.StatusNotFound)
+			return
+		}
+		trackers = []*app.Tracker{t}
+	}
+
+	if !acceptsHTML(r) {
+		s.syncNow(w, r, trackers)
+		return
+	}
+	s.startSync(trackers)
+	redirect(w, r, "Sync started. Refresh the page to see its outcome.")
+}
+
+// startSync syncs the provided profiles in the background, one after
+// the other.
+func (s *Server) startSync(trackers []*app.Tracker) {
+	go func() {
+		for _, t := range trackers {
+			if s.ctx.Err() != nil {
+				return
+			}
+			_, _ = s.sync(s.ctx, t) // The outcome is logged by sync
+		}
+	}()
+}
+
+// retry makes an activity of the retry queue due, and starts a sync
+// of its profile to retry it.
+func (s *Server) retry(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tracker(r.FormValue("profile"))
+	if !ok {
+		http.Error(w, "profile not found", http.StatusNotFound)
+		return
+	}
+	err := t.Do(r.Context(), func() error {
+		return t.Activity.Retry(r.FormValue("key"), time.Now())
+	})
+	if err != nil {
+		actionFailed(w, r, err)
+		return
+	}
+
+	s.startSync([]*app.Tracker{t})
+	redirect(w, r, "Retrying. Refresh the page to see its outcome.")
+}
+
+// assign marks an activity as watched using the Trakt ID given by the
+// user.
+func (s *Server) assign(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tracker(r.FormValue("profile"))
+	if !ok {
+		http.Error(w, "profile not found", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("trakt_id"))
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid Trakt ID", http.StatusBadRequest)
+		return
+	}
+	match := activitytracker.NewManualMatch(r.FormValue("kind") == "episode", id)
+
+	apply(w, r, t, func(ctx context.Context) (*activitytracker.Summary, error) {
+		return t.Activity.Assign(ctx, r.FormValue("key"), match)
+	})
+}
+
+// pick marks an activity of the review queue as watched, using the
+// candidate picked by the user.
+func (s *Server) pick(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tracker(r.FormValue("profile"))
+	if !ok {
+		http.Error(w, "profile not found", http.StatusNotFound)
+		return
+	}
+	candidate, err := strconv.Atoi(r.FormValue("candidate"))
+	if err != nil {
+		http.Error(w, "invalid candidate", http.StatusBadRequest)
+		return
+	}
+
+	apply(w, r, t, func(ctx context.Context) (*activitytracker.Summary, error) {
+		var entry *activitytracker.ReviewEntry
+		for _, e := range t.Activity.Reviews() {
+			if e.Activity.HistoryItem().Key() == r.FormValue("key") {
+				entry = e
+			}
+		}
+		if entry == nil {
+			return nil, activitytracker.ErrNotQueued
+		}
+		if candidate < 0 || candidate >= len(entry.Candidates) {
+			return nil, errors.New("candidate not found")
+		}
+		return t.Activity.ApplyReviews(ctx, []activitytracker.ReviewDecision{{
+			Entry:    entry,
+			Match:    entry.Candidates[candidate],
+			Override: r.FormValue("override") != "",
+		}})
+	})
+}
+
+// apply runs an action sending activity to Trakt, and reports its
+// outcome to the user.
+func apply(w http.ResponseWriter, r *http.Request, t *app.Tracker, action func(context.Context) (*activitytracker.Summary, error)) {
+	var summary *activitytracker.Summary
+	err := t.Do(r.Context(), func() (err error) {
+		summary, err = action(r.Context())
+		return err
+	})
+	if err != nil {
+		actionFailed(w, r, err)
+		return
+	}
+	if !summary.Complete() {
+		redirect(w, r, "Trakt didn't add the activity: "+summary.String())
+		return
+	}
+	redirect(w, r, "Added to Trakt.")
+}
+
+// actionFailed reports to the user an action that failed.
+func actionFailed(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, activitytracker.ErrNotQueued) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	slog.ErrorContext(r.Context(), "web UI action failed", "path", r.URL.Path, "error", err.Error())
+	redirect(w, r, "Failed: "+err.Error())
+}
@@ -0,0 +1,165 @@
+// Package web contains the web UI of the service, used to follow the
+// syncs and to fix the activity that couldn't be synced.
+package web
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Nivl/trakt-netflix/internal/activitytracker"
+	"github.com/Nivl/trakt-netflix/internal/app"
+	"github.com/Nivl/trakt-netflix/internal/secret"
+)
+
+// shutdownTimeout is how long the requests in flight have to complete
+// when the server stops.
+const shutdownTimeout = 5 * time.Second
+
+//go:embed templates
+var templatesFS embed.FS
+
+// templates contains the pages of the web UI.
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"formatTime": formatTime,
+	"assignForm": newAssignForm,
+}).ParseFS(templatesFS, "templates/*.html"))
+
+// Config contains the configuration of the web UI.
+type Config struct {
+	// Addr is the address the web UI listens on (ex. ":8080"). The web
+	// UI is disabled if empty.
+	Addr string `env:"ADDR"`
+	// Username is the username of the basic authentication.
+	Username string `env:"USERNAME,default=admin"`
+	// Password is the password of the basic authentication. The basic
+	// authentication is disabled if empty.
+	Password secret.Secret `env:"PASSWORD"`
+	// Token can be used instead of the basic authentication, either
+	// with an "Authorization: Bearer <token>" header, or by opening
+	// /?token=<token> once.
+	Token secret.Secret `env:"TOKEN"`
+}
+
+// SyncFunc syncs a profile, and returns the outcome of the sync.
+// app.ErrSyncInProgress is expected if the profile is already being
+// synced.
+type SyncFunc func(ctx context.Context, t *app.Tracker) (*activitytracker.Summary, error)
+
+// Server represents the web UI.
+type Server struct {
+	cfg      Config
+	trackers []*app.Tracker
+	sync     SyncFunc
+	// ctx is the context of the server, used by the operations that
+	// outlive a request.
+	ctx context.Context //nolint:containedctx // The syncs started from the UI must not stop with their request
+}
+
+// New returns a new web UI serving the given profiles. sync is used
+// to sync a profile when the user asks for it.
+func New(cfg Config, trackers []*app.Tracker, sync SyncFunc) (*Server, error) {
+	if cfg.Password.IsEmpty() && cfg.Token.IsEmpty() {
+		return nil, errors.New("a password or a token is required to protect the web UI")
+	}
+	return &Server{
+		cfg:      cfg,
+		trackers: trackers,
+		sync:     sync,
+		ctx:      context.Background(),
+	}, nil
+}
+
+// Handler returns the handler serving the web UI.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /status", s.status)
+	mux.HandleFunc("POST /sync", s.forceSync)
+	mux.HandleFunc("POST /retry", s.retry)
+	mux.HandleFunc("POST /assign", s.assign)
+	mux.HandleFunc("POST /pick", s.pick)
+
+	// The health check is used by tools that don't have the credentials
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", healthz)
+	root.Handle("/", s.authenticate(sameOrigin(mux)))
+	return root
+}
+
+// ListenAndServe serves the web UI until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.ctx = ctx
+	srv := &http.Server{ //nolint:exhaustruct // The zero values are the defaults
+		Addr:              s.cfg.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	slog.InfoContext(ctx, "Web UI listening", "addr", s.cfg.Addr)
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("listen on %s: %w", s.cfg.Addr, err)
+	case <-ctx.Done():
+	}
+
+	// The server context is done, so we need a new one to shut down
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
+
+// tracker returns the tracker of the profile with the given name.
+func (s *Server) tracker(name string) (*app.Tracker, bool) {
+	for _, t := range s.trackers {
+		if t.Profile.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// redirect sends the user back to the index, with a message.
+func redirect(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/?"+url.Values{"msg": {msg}}.Encode(), http.StatusSeeOther)
+}
+
+// formatTime formats a time for the web UI.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// assignForm contains the data of the form used to assign a Trakt ID
+// to an activity.
+type assignForm struct {
+	Profile string
+	// Key is the key of the history item of the activity.
+	Key string
+}
+
+// newAssignForm returns the data of the form used to assign a Trakt ID
+// to the activity with the given key.
+func newAssignForm(profile, key string) assignForm {
+	return assignForm{Profile: profile, Key: key}
+}
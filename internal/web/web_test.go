@@ -0,0 +1,212 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nivl/trakt-netflix/internal/secret"
+	"github.com/Nivl/trakt-netflix/internal/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	password = "hunter2"
+	token    = "my-token"
+)
+
+func newHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	srv, err := web.New(web.Config{
+		Addr:     ":0",
+		Username: "admin",
+		Password: secret.NewSecret(password),
+		Token:    secret.NewSecret(token),
+	}, nil, nil)
+	require.NoError(t, err)
+	return srv.Handler()
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := web.New(web.Config{
+		Addr:     ":0",
+		Username: "admin",
+		Password: secret.NewSecret(""),
+		Token:    secret.NewSecret(""),
+	}, nil, nil)
+	require.Error(t, err, "the web UI should not be usable without credentials")
+}
+
+func TestAuthentication(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc           string
+		setup          func(r *http.Request)
+		expectedStatus int
+	}{
+		{
+			desc:           "no credentials",
+			setup:          func(*http.Request) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc: "valid basic auth",
+			setup: func(r *http.Request) {
+				r.SetBasicAuth("admin", password)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc: "invalid basic auth",
+			setup: func(r *http.Request) {
+				r.SetBasicAuth("admin", "nope")
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc: "valid bearer token",
+			setup: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc: "invalid bearer token",
+			setup: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer nope")
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			desc: "valid cookie",
+			setup: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "token", Value: token})
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tc.setup(req)
+			rec := httptest.NewRecorder()
+			newHandler(t).ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestTokenInURL(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/?token="+token+"&msg=hi", nil)
+	rec := httptest.NewRecorder()
+	newHandler(t).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/?msg=hi", rec.Header().Get("Location"), "the token should be removed from the URL")
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, token, cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+}
+
+func TestSameOrigin(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			desc:           "no browser headers",
+			headers:        map[string]string{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "same origin",
+			headers:        map[string]string{"Origin": "http://example.com", "Sec-Fetch-Site": "same-origin"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "cross site",
+			headers:        map[string]string{"Sec-Fetch-Site": "cross-site"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			desc:           "other origin",
+			headers:        map[string]string{"Origin": "http://evil.example.org"},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// There are no profiles, so syncing them all does nothing
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/sync", strings.NewReader(""))
+			req.Header.Set("Authorization", "Bearer "+token)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			newHandler(t).ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	newHandler(t).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "the health check should not require credentials")
+}
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc         string
+		method       string
+		path         string
+		expectedBody string
+	}{
+		{
+			desc:         "sync",
+			method:       http.MethodPost,
+			path:         "/sync",
+			expectedBody: `{"profiles":[]}`,
+		},
+		{
+			desc:         "status",
+			method:       http.MethodGet,
+			path:         "/status",
+			expectedBody: `{"profiles":[]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			newHandler(t).ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}